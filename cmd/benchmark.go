@@ -1,16 +1,14 @@
 package cmd
 
 import (
-	"encoding/csv"
-	"encoding/json"
-	"encoding/xml"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"reflect"
 
 	pewpew "github.com/bengadbois/pewpew/lib"
-	humanize "github.com/dustin/go-humanize"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,10 +17,17 @@ var benchmarkCmd = &cobra.Command{
 	Use:     "benchmark URL...",
 	Aliases: []string{"bench"},
 	Short:   "Run benchmark tests",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindSharedFlags(cmd, "duration", "arrival")
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		benchmarkCfg := pewpew.BenchmarkConfig{}
-		err := viper.Unmarshal(&benchmarkCfg)
+		err := viper.Unmarshal(&benchmarkCfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+			stringToStagesHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		)))
 		if err != nil {
 			fmt.Println(err)
 			return errors.New("could not parse config file")
@@ -33,172 +38,103 @@ var benchmarkCmd = &cobra.Command{
 		benchmarkCfg.Verbose = viper.GetBool("verbose")
 		benchmarkCfg.RPS = viper.GetInt("rps")
 		benchmarkCfg.Duration = viper.GetInt("duration")
+		benchmarkCfg.Arrival = viper.GetString("arrival")
+		benchmarkCfg.MaxInFlight = viper.GetInt("maxInFlight")
 
-		//URLs are handled differently that other config options
-		//command line specifying URLs take higher precedence than config URLs
-
-		//check either set via config or command line
-		if len(benchmarkCfg.Targets) == 0 && len(args) < 1 {
-			return errors.New("requires URL")
+		benchmarkCfg.Targets, err = buildTargets(benchmarkCfg.Targets, args)
+		if err != nil {
+			return err
 		}
 
-		//if URLs are set on command line, use that for Targets instead of config
-		if len(args) >= 1 {
-			benchmarkCfg.Targets = make([]pewpew.Target, len(args))
-			for i := range benchmarkCfg.Targets {
-				benchmarkCfg.Targets[i].URL = args[i]
-				//use global configs instead of the config file's individual target settings
-				benchmarkCfg.Targets[i].RegexURL = viper.GetBool("regex")
-				benchmarkCfg.Targets[i].Options.DNSPrefetch = viper.GetBool("dns-prefetch")
-				benchmarkCfg.Targets[i].Options.Timeout = viper.GetString("timeout")
-				benchmarkCfg.Targets[i].Options.Method = viper.GetString("request-method")
-				benchmarkCfg.Targets[i].Options.Body = viper.GetString("body")
-				benchmarkCfg.Targets[i].Options.BodyFilename = viper.GetString("body-file")
-				benchmarkCfg.Targets[i].Options.Headers = viper.GetString("headers")
-				benchmarkCfg.Targets[i].Options.Cookies = viper.GetString("cookies")
-				benchmarkCfg.Targets[i].Options.UserAgent = viper.GetString("user-agent")
-				benchmarkCfg.Targets[i].Options.BasicAuth = viper.GetString("basic-auth")
-				benchmarkCfg.Targets[i].Options.Compress = viper.GetBool("compress")
-				benchmarkCfg.Targets[i].Options.KeepAlive = viper.GetBool("keepalive")
-				benchmarkCfg.Targets[i].Options.FollowRedirects = viper.GetBool("follow-redirects")
-				benchmarkCfg.Targets[i].Options.NoHTTP2 = viper.GetBool("no-http2")
-				benchmarkCfg.Targets[i].Options.EnforceSSL = viper.GetBool("enforce-ssl")
-			}
-		} else {
-			//set non-URL target settings
-			//walk through viper.Get() because that will show which were
-			//explictly set instead of guessing at zero-valued defaults
-			for i, target := range viper.Get("targets").([]interface{}) {
-				targetMapVals := target.(map[string]interface{})
-
-				if _, set := targetMapVals["RegexURL"]; !set {
-					benchmarkCfg.Targets[i].RegexURL = viper.GetBool("regex")
-				}
-				if _, set := targetMapVals["DNSPrefetch"]; !set {
-					benchmarkCfg.Targets[i].Options.DNSPrefetch = viper.GetBool("dns-prefetch")
-				}
-				if _, set := targetMapVals["Timeout"]; !set {
-					benchmarkCfg.Targets[i].Options.Timeout = viper.GetString("timeout")
-				}
-				if _, set := targetMapVals["Method"]; !set {
-					benchmarkCfg.Targets[i].Options.Method = viper.GetString("request-method")
-				}
-				if _, set := targetMapVals["Body"]; !set {
-					benchmarkCfg.Targets[i].Options.Body = viper.GetString("body")
-				}
-				if _, set := targetMapVals["BodyFilename"]; !set {
-					benchmarkCfg.Targets[i].Options.BodyFilename = viper.GetString("bodyFile")
-				}
-				if _, set := targetMapVals["Headers"]; !set {
-					benchmarkCfg.Targets[i].Options.Headers = viper.GetString("headers")
-				}
-				if _, set := targetMapVals["Cookies"]; !set {
-					benchmarkCfg.Targets[i].Options.Cookies = viper.GetString("cookies")
-				}
-				if _, set := targetMapVals["UserAgent"]; !set {
-					benchmarkCfg.Targets[i].Options.UserAgent = viper.GetString("userAgent")
-				}
-				if _, set := targetMapVals["BasicAuth"]; !set {
-					benchmarkCfg.Targets[i].Options.BasicAuth = viper.GetString("basicAuth")
-				}
-				if _, set := targetMapVals["Compress"]; !set {
-					benchmarkCfg.Targets[i].Options.Compress = viper.GetBool("compress")
-				}
-				if _, set := targetMapVals["KeepAlive"]; !set {
-					benchmarkCfg.Targets[i].Options.KeepAlive = viper.GetBool("keepalive")
-				}
-				if _, set := targetMapVals["FollowRedirects"]; !set {
-					benchmarkCfg.Targets[i].Options.FollowRedirects = viper.GetBool("followredirects")
-				}
-				if _, set := targetMapVals["NoHTTP2"]; !set {
-					benchmarkCfg.Targets[i].Options.NoHTTP2 = viper.GetBool("no-http2")
-				}
-				if _, set := targetMapVals["EnforceSSL"]; !set {
-					benchmarkCfg.Targets[i].Options.EnforceSSL = viper.GetBool("enforce-ssl")
-				}
-			}
+		err = validateOutputFlags()
+		if err != nil {
+			return err
 		}
 
-		targetRequestStats, err := pewpew.RunBenchmark(benchmarkCfg, os.Stdout)
+		//past this point, failures aren't from misuse of the command
+		cmd.SilenceUsage = true
+
+		var targetRequestStats [][]pewpew.RequestStat
+		interrupted, err := runObserved(benchmarkCfg.Targets, func(ctx context.Context, observers []pewpew.Observer) error {
+			benchmarkCfg.Observers = append(benchmarkCfg.Observers, observers...)
+			var runErr error
+			targetRequestStats, runErr = pewpew.RunBenchmarkContext(ctx, benchmarkCfg, os.Stdout)
+			return runErr
+		})
 		if err != nil {
 			return err
 		}
 
-		fmt.Print("\n----Summary----\n\n")
+		globalStats := printSummaries(benchmarkCfg.Targets, targetRequestStats)
 
-		//only print individual target data if multiple targets
-		if len(benchmarkCfg.Targets) > 1 {
-			for idx, target := range benchmarkCfg.Targets {
-				//info about the request
-				fmt.Printf("----Target %d: %s %s\n", idx+1, target.Options.Method, target.URL)
-				reqStats := pewpew.CreateRequestsStats(targetRequestStats[idx])
-				fmt.Println(pewpew.CreateTextSummary(reqStats))
+		//break down by stage to show where along the load profile things changed
+		if len(benchmarkCfg.Stages) > 1 {
+			stageStats := make([][]pewpew.RequestStat, len(benchmarkCfg.Stages))
+			for _, stat := range globalStats {
+				stageStats[stat.Stage] = append(stageStats[stat.Stage], stat)
+			}
+			for idx, stage := range benchmarkCfg.Stages {
+				fmt.Printf("----Stage %d: %s\n", idx+1, stage)
+				fmt.Println(pewpew.CreateTextSummary(pewpew.CreateRequestsStats(stageStats[idx])))
 			}
 		}
 
-		//combine individual targets to a total one
-		globalStats := []pewpew.RequestStat{}
-		for i := range benchmarkCfg.Targets {
-			globalStats = append(globalStats, targetRequestStats[i]...)
+		err = printTimeSeries(globalStats)
+		if err != nil {
+			return err
 		}
-		if len(benchmarkCfg.Targets) > 1 {
-			fmt.Println("----Global----")
+
+		passed, err := printThresholds("Target", benchmarkCfg.Targets, targetRequestStats, globalStats, benchmarkCfg.Thresholds)
+		if err != nil {
+			return err
 		}
-		reqStats := pewpew.CreateRequestsStats(globalStats)
-		fmt.Println(pewpew.CreateTextSummary(reqStats))
-
-		if viper.GetString("output-json") != "" {
-			filename := viper.GetString("output-json")
-			fmt.Print("Writing full result data to: " + filename + " ...")
-			json, _ := json.MarshalIndent(globalStats, "", "    ")
-			err = ioutil.WriteFile(filename, json, 0644)
-			if err != nil {
-				return fmt.Errorf("failed to write full result data to %s: %w", filename, err)
-			}
-			fmt.Println("finished!")
+
+		err = writeOutputFiles(targetRequestStats, globalStats)
+		if err != nil {
+			return err
 		}
-		//write out csv
-		if viper.GetString("output-csv") != "" {
-			filename := viper.GetString("output-csv")
-			fmt.Print("Writing full result data to: " + filename + " ...")
-			file, err := os.Create(filename)
-			if err != nil {
-				return fmt.Errorf("failed to write full result data to %s: %w", filename, err)
+		settings := []pewpew.ReportSetting{}
+		if len(benchmarkCfg.Stages) > 0 {
+			for idx, stage := range benchmarkCfg.Stages {
+				settings = append(settings, pewpew.ReportSetting{Name: fmt.Sprintf("Stage %d", idx+1), Value: stage.String()})
 			}
-			defer file.Close()
-
-			writer := csv.NewWriter(file)
-
-			for _, req := range globalStats {
-				line := []string{
-					req.StartTime.String(),
-					fmt.Sprintf("%d", req.Duration),
-					fmt.Sprintf("%d", req.StatusCode),
-					humanize.Bytes(uint64(req.DataTransferred)),
-				}
-				err := writer.Write(line)
-				if err != nil {
-					return fmt.Errorf("failed to write full result data to %s: %w", filename, err)
-				}
-			}
-			defer writer.Flush()
-			fmt.Println("finished!")
+		} else {
+			settings = append(settings,
+				pewpew.ReportSetting{Name: "RPS", Value: fmt.Sprintf("%d", benchmarkCfg.RPS)},
+				pewpew.ReportSetting{Name: "Duration", Value: fmt.Sprintf("%d seconds", benchmarkCfg.Duration)})
 		}
-		//write out xml
-		if viper.GetString("output-xml") != "" {
-			filename := viper.GetString("output-xml")
-			fmt.Print("Writing full result data to: " + filename + " ...")
-			xml, _ := xml.MarshalIndent(globalStats, "", "    ")
-			err = ioutil.WriteFile(viper.GetString("output-xml"), xml, 0644)
-			if err != nil {
-				return fmt.Errorf("failed to write full result data to %s: %w", filename, err)
-			}
-			fmt.Println("finished!")
+		settings = append(settings,
+			pewpew.ReportSetting{Name: "Arrival", Value: benchmarkCfg.Arrival},
+			pewpew.ReportSetting{Name: "Max in flight", Value: fmt.Sprintf("%d", benchmarkCfg.MaxInFlight)},
+			pewpew.ReportSetting{Name: "Targets", Value: fmt.Sprintf("%d", len(benchmarkCfg.Targets))})
+		err = writeHTMLReport("Benchmark test", settings, benchmarkCfg.Targets, targetRequestStats)
+		if err != nil {
+			return err
+		}
+		if interrupted {
+			return errors.New("run was interrupted")
+		}
+		if !passed {
+			return errors.New("one or more thresholds failed")
 		}
 		return nil
 	},
 }
 
+// stringToStagesHookFunc allows Stages to be set with the compact
+// string syntax of pewpew.ParseStages, both on the command line and in config files
+func stringToStagesHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf([]pewpew.Stage{}) {
+			return data, nil
+		}
+		if data.(string) == "" {
+			return []pewpew.Stage{}, nil
+		}
+		return pewpew.ParseStages(data.(string))
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(benchmarkCmd)
 	benchmarkCmd.Flags().Int("rps", pewpew.DefaultRPS, "Requests per second to make.")
@@ -210,7 +146,19 @@ func init() {
 	}
 
 	benchmarkCmd.Flags().IntP("duration", "d", pewpew.DefaultConcurrency, "Number of seconds to send requests. Total benchmark test duration will be longer due to waiting for requests to finish.")
-	err = viper.BindPFlag("duration", benchmarkCmd.Flags().Lookup("duration"))
+
+	benchmarkCmd.Flags().String("stages", "", "Comma separated stages to vary the rate over time, as DURATION:RPS to hold a rate or DURATION:FROM-TO to ramp, eg. '60s:0-500,5m:500,10s:2000,60s:2000-0'. Overrides --rps and --duration.")
+	err = viper.BindPFlag("stages", benchmarkCmd.Flags().Lookup("stages"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	benchmarkCmd.Flags().String("arrival", pewpew.DefaultArrival, "How requests are spaced out within each second: 'burst' sends them all at the start of the second, 'uniform' spaces them evenly, 'poisson' spaces them randomly like independent users.")
+
+	benchmarkCmd.Flags().Int("max-in-flight", 0, "Most requests in flight at once per target. Requests due while at the limit are counted as dropped instead of being sent late. 0 means no limit.")
+	err = viper.BindPFlag("maxInFlight", benchmarkCmd.Flags().Lookup("max-in-flight"))
 	if err != nil {
 		fmt.Println("failed to configure flags")
 		fmt.Println(err)
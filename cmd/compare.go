@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare BASE CANDIDATE",
+	Short: "Compare the results of a candidate run to a baseline run",
+	Long: `Compare reads two results files written with any of the --output-* file flags,
+and prints the summary statistics of both side by side, along with a Mann-Whitney U
+test of whether their latency distributions differ significantly.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxRegression, err := parseMaxRegression(viper.GetString("maxRegression"))
+		if err != nil {
+			return err
+		}
+
+		//past this point, failures aren't from misuse of the command
+		cmd.SilenceUsage = true
+
+		base, err := readResultsFile(args[0])
+		if err != nil {
+			return err
+		}
+		candidate, err := readResultsFile(args[1])
+		if err != nil {
+			return err
+		}
+		comparison := pewpew.CompareRequestStats(requestStatsOf(base), requestStatsOf(candidate))
+
+		fmt.Printf("Base:      %s (%d results)\n", args[0], len(base))
+		fmt.Printf("Candidate: %s (%d results)\n", args[1], len(candidate))
+		fmt.Print("\n----Comparison----\n\n")
+		fmt.Println(pewpew.CreateComparisonSummary(comparison))
+
+		if maxRegression < 0 {
+			return nil
+		}
+		regressions := comparison.Regressions(maxRegression)
+		if len(regressions) == 0 {
+			fmt.Printf("No regressions over %g%%\n", maxRegression)
+			return nil
+		}
+		fmt.Printf("Regressions over %g%%\n", maxRegression)
+		for _, m := range regressions {
+			if m.Unit == "%" {
+				fmt.Printf("%s: worse by %.2f percentage points\n", m.Name, m.Regression())
+			} else {
+				fmt.Printf("%s: worse by %.2f%%\n", m.Name, m.Regression())
+			}
+		}
+		return fmt.Errorf("candidate regressed by more than %g%%", maxRegression)
+	},
+}
+
+// parseMaxRegression parses a percentage like "10%" or "10", or returns -1 if it isn't set
+func parseMaxRegression(str string) (float64, error) {
+	if str == "" {
+		return -1, nil
+	}
+	max, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(str), "%"), 64)
+	if err != nil || max < 0 {
+		return 0, fmt.Errorf("invalid max regression %q, must be a percentage like 10%%", str)
+	}
+	return max, nil
+}
+
+// requestStatsOf is the RequestStats of every target of records
+func requestStatsOf(records []resultRecord) []pewpew.RequestStat {
+	stats := make([]pewpew.RequestStat, len(records))
+	for i, r := range records {
+		stats[i] = r.requestStat()
+	}
+	return stats
+}
+
+func init() {
+	RootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().String("max-regression", "", "Fail if the candidate's throughput or mean, 50%, 90%, 95% or 99% latency is worse by more than this percentage, or its error rate is higher by more than this many percentage points, eg. '10%'.")
+	err := viper.BindPFlag("maxRegression", compareCmd.Flags().Lookup("max-regression"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert requests from other tools to pewpew config",
+}
+
+var convertCurlCmd = &cobra.Command{
+	Use:   "curl [CURL COMMAND]",
+	Short: "Convert a curl command line to a pewpew config file",
+	Long: `Convert curl parses a curl command line and prints a pewpew.json config file with
+a target that sends the same request. The command can be given as one quoted
+argument, as separate arguments, or on standard input when there are none, eg.
+
+  pewpew convert curl "curl -X POST -H 'Content-Type: application/json' -d '{}' http://localhost/api"
+  pbpaste | pewpew convert curl > curl.json && mv curl.json pewpew.json`,
+	//curl's options are part of the command to convert, not options of this command
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
+			return cmd.Help()
+		}
+		var target pewpew.Target
+		var err error
+		switch len(args) {
+		case 0:
+			command, readErr := ioutil.ReadAll(os.Stdin)
+			if readErr != nil {
+				return fmt.Errorf("failed to read curl command: %w", readErr)
+			}
+			target, err = pewpew.ParseCurl(string(command))
+		case 1:
+			target, err = pewpew.ParseCurl(args[0])
+		default:
+			target, err = pewpew.ParseCurlArgs(args)
+		}
+		if err != nil {
+			return err
+		}
+
+		var b strings.Builder
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(curlConfig{Targets: []curlConfigTarget{newCurlConfigTarget(target)}})
+		if err != nil {
+			return err
+		}
+		fmt.Print(b.String())
+		return nil
+	},
+}
+
+// curlConfig is a config file with the target of a curl command
+type curlConfig struct {
+	Targets []curlConfigTarget
+}
+
+// curlConfigTarget is a Target in the config file format. Options that curl
+// defaults differently than pewpew are always written.
+type curlConfigTarget struct {
+	URL             string
+	Method          string
+	Body            string            `json:",omitempty"`
+	BodyFilename    string            `json:",omitempty"`
+	RawHeaders      map[string]string `json:",omitempty"`
+	Cookies         string            `json:",omitempty"`
+	UserAgent       string
+	BasicAuth       string `json:",omitempty"`
+	Timeout         string `json:",omitempty"`
+	Compress        bool
+	FollowRedirects bool
+	EnforceSSL      bool
+	NoHTTP2         bool `json:",omitempty"`
+}
+
+func newCurlConfigTarget(t pewpew.Target) curlConfigTarget {
+	return curlConfigTarget{
+		URL:             t.URL,
+		Method:          t.Options.Method,
+		Body:            t.Options.Body,
+		BodyFilename:    t.Options.BodyFilename,
+		RawHeaders:      t.Options.RawHeaders,
+		Cookies:         t.Options.Cookies,
+		UserAgent:       t.Options.UserAgent,
+		BasicAuth:       t.Options.BasicAuth,
+		Timeout:         t.Options.Timeout,
+		Compress:        t.Options.Compress,
+		FollowRedirects: t.Options.FollowRedirects,
+		EnforceSSL:      t.Options.EnforceSSL,
+		NoHTTP2:         t.Options.NoHTTP2,
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(convertCmd)
+	convertCmd.AddCommand(convertCurlCmd)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/viper"
+)
+
+// jsonlWriter is an Observer that writes each request to a file as soon as it
+// finishes, so results are kept even if the run never gets to the end
+type jsonlWriter struct {
+	lock     sync.Mutex
+	filename string
+	file     *os.File
+	err      error //first error writing to file
+}
+
+// createJSONLWriter creates the --output-jsonl file, or returns nil if it isn't set
+func createJSONLWriter() (*jsonlWriter, error) {
+	filename := viper.GetString("output-jsonl")
+	if filename == "" {
+		return nil, nil
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	fmt.Println("Writing each result as it completes to: " + filename)
+	return &jsonlWriter{filename: filename, file: file}, nil
+}
+
+// RequestStarted implements pewpew.Observer
+func (j *jsonlWriter) RequestStarted(target int) {}
+
+// RequestFinished implements pewpew.Observer
+func (j *jsonlWriter) RequestFinished(target int, stat pewpew.RequestStat) {
+	//cut short by an interrupt, so isn't part of the results
+	if errors.Is(stat.Error, context.Canceled) {
+		return
+	}
+	line, err := json.Marshal(newResultRecord(target, stat))
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.err != nil {
+		return
+	}
+	//written straight to the file, unbuffered, so nothing is lost if the process dies
+	_, j.err = j.file.Write(line)
+}
+
+// close closes the file, returning the first error from writing to it.
+// Closing a nil jsonlWriter does nothing.
+func (j *jsonlWriter) close() error {
+	if j == nil {
+		return nil
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	err := j.file.Close()
+	if j.err != nil {
+		err = j.err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results to %s: %w", j.filename, err)
+	}
+	return nil
+}
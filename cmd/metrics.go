@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/viper"
+)
+
+// startMetricsServer serves live Prometheus metrics of the targets at /metrics
+// while the run is in progress, if --metrics-addr is set.
+// The returned function stops the server.
+func startMetricsServer(targets []pewpew.Target) ([]pewpew.Observer, func(), error) {
+	addr := viper.GetString("metrics-addr")
+	if addr == "" {
+		return nil, func() {}, nil
+	}
+	//listen up front so a bad address fails before the run starts
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	metrics := pewpew.NewMetrics(targets)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	server := &http.Server{Handler: mux}
+	go func() {
+		//Serve only returns ErrServerClosed when stopped on purpose
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("metrics server stopped: " + err.Error())
+		}
+	}()
+	fmt.Printf("Serving metrics at http://%s/metrics\n", listener.Addr())
+	stop := func() {
+		server.Close()
+		//Serve may not have started yet, so its listener isn't closed by Close
+		listener.Close()
+	}
+	return []pewpew.Observer{metrics}, stop, nil
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/viper"
+)
+
+// printSummaries prints a summary per target, if there are multiple, and one
+// for all targets combined. It returns the combined stats of all targets.
+func printSummaries(targets []pewpew.Target, targetRequestStats [][]pewpew.RequestStat) []pewpew.RequestStat {
+	fmt.Print("\n----Summary----\n\n")
+
+	//only print individual target data if multiple targets
+	if len(targets) > 1 {
+		for idx, target := range targets {
+			//nothing to summarize, such as a target filtered out of a report
+			if len(targetRequestStats[idx]) == 0 {
+				continue
+			}
+			//info about the request
+			fmt.Printf("----Target %d: %s %s\n", idx+1, target.Options.Method, target.URL)
+			reqStats := pewpew.CreateRequestsStats(targetRequestStats[idx])
+			fmt.Println(pewpew.CreateTextSummary(reqStats))
+		}
+	}
+
+	//combine individual targets to a total one
+	globalStats := []pewpew.RequestStat{}
+	for i := range targets {
+		globalStats = append(globalStats, targetRequestStats[i]...)
+	}
+	if len(targets) > 1 {
+		fmt.Println("----Global----")
+	}
+	reqStats := pewpew.CreateRequestsStats(globalStats)
+	fmt.Println(pewpew.CreateTextSummary(reqStats))
+	return globalStats
+}
+
+// validateOutputFlags checks the output settings before the test runs,
+// so a typo doesn't waste a whole test
+func validateOutputFlags() error {
+	if _, err := timeSeriesInterval(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// timeSeriesInterval is the length of each interval of the time series
+func timeSeriesInterval() (time.Duration, error) {
+	interval, err := time.ParseDuration(viper.GetString("timeseries-interval"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse time series interval: %w", err)
+	}
+	if interval <= 0 {
+		return 0, errors.New("time series interval must be greater than zero")
+	}
+	return interval, nil
+}
+
+// printTimeSeries prints the time series of all targets combined, if enabled
+func printTimeSeries(globalStats []pewpew.RequestStat) error {
+	if !viper.GetBool("timeseries") {
+		return nil
+	}
+	interval, err := timeSeriesInterval()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("----Time Series (%s intervals)----\n\n", interval)
+	fmt.Println(pewpew.CreateTimeSeriesTable(pewpew.CreateTimeSeries(globalStats, interval)))
+	return nil
+}
+
+// printThresholds checks each target's thresholds against its own results and
+// the global thresholds against all results combined, printing a pass/fail table.
+// Targets are named by label and their number, eg. "Target 1".
+// It returns whether every threshold passed.
+func printThresholds(label string, targets []pewpew.Target, targetRequestStats [][]pewpew.RequestStat, globalStats []pewpew.RequestStat, globalThresholds []string) (bool, error) {
+	results := []pewpew.ThresholdResult{}
+	for idx, target := range targets {
+		if len(target.Thresholds) == 0 {
+			continue
+		}
+		targetResults, err := pewpew.CheckThresholds(fmt.Sprintf("%s %d", label, idx+1), target.Thresholds, pewpew.CreateRequestsStats(targetRequestStats[idx]))
+		if err != nil {
+			return false, err
+		}
+		results = append(results, targetResults...)
+	}
+	if len(globalThresholds) > 0 {
+		globalResults, err := pewpew.CheckThresholds("Global", globalThresholds, pewpew.CreateRequestsStats(globalStats))
+		if err != nil {
+			return false, err
+		}
+		results = append(results, globalResults...)
+	}
+	if len(results) == 0 {
+		return true, nil
+	}
+
+	fmt.Println(pewpew.CreateThresholdSummary(results))
+	for _, result := range results {
+		if !result.Passed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// writeOutputFiles writes the full result data to each of the requested output files
+func writeOutputFiles(targetRequestStats [][]pewpew.RequestStat, globalStats []pewpew.RequestStat) error {
+	records := toResultRecords(targetRequestStats)
+	if viper.GetString("output-json") != "" {
+		err := writeResultsFile(viper.GetString("output-json"), writeResultsJSON, records)
+		if err != nil {
+			return err
+		}
+	}
+	if viper.GetString("output-csv") != "" {
+		err := writeResultsFile(viper.GetString("output-csv"), writeResultsCSV, records)
+		if err != nil {
+			return err
+		}
+	}
+	if viper.GetString("output-xml") != "" {
+		err := writeResultsFile(viper.GetString("output-xml"), writeResultsXML, records)
+		if err != nil {
+			return err
+		}
+	}
+	if viper.GetString("output-timeseries-csv") != "" || viper.GetString("output-timeseries-json") != "" {
+		interval, err := timeSeriesInterval()
+		if err != nil {
+			return err
+		}
+		err = writeTimeSeriesFiles(pewpew.CreateTimeSeries(globalStats, interval))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTimeSeriesFiles writes the time series to each of the requested output files
+func writeTimeSeriesFiles(series []pewpew.TimeSeriesBucket) error {
+	if viper.GetString("output-timeseries-json") != "" {
+		filename := viper.GetString("output-timeseries-json")
+		fmt.Print("Writing time series to: " + filename + " ...")
+		json, _ := json.MarshalIndent(series, "", "    ")
+		err := ioutil.WriteFile(filename, json, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to write time series to %s: %w", filename, err)
+		}
+		fmt.Println("finished!")
+	}
+	if viper.GetString("output-timeseries-csv") != "" {
+		filename := viper.GetString("output-timeseries-csv")
+		fmt.Print("Writing time series to: " + filename + " ...")
+		file, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("failed to write time series to %s: %w", filename, err)
+		}
+		defer file.Close()
+
+		//a column for every status code seen in any interval
+		seen := make(map[int]bool)
+		for _, bucket := range series {
+			for code := range bucket.StatusCodes {
+				seen[code] = true
+			}
+		}
+		var codes []int
+		for code := range seen {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+
+		header := []string{"start", "offset_ms", "requests", "rps", "errors", "dropped",
+			"mean_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "max_ms"}
+		for _, code := range codes {
+			header = append(header, fmt.Sprintf("status_%d", code))
+		}
+		writer := csv.NewWriter(file)
+		err = writer.Write(header)
+		if err != nil {
+			return fmt.Errorf("failed to write time series to %s: %w", filename, err)
+		}
+		for _, bucket := range series {
+			line := []string{
+				bucket.Start.Format(time.RFC3339Nano),
+				fmt.Sprintf("%d", bucket.Offset.Milliseconds()),
+				fmt.Sprintf("%d", bucket.Requests),
+				fmt.Sprintf("%.2f", bucket.RPS),
+				fmt.Sprintf("%d", bucket.Errors),
+				fmt.Sprintf("%d", bucket.Dropped),
+				fmt.Sprintf("%.3f", float64(bucket.MeanDuration)/float64(time.Millisecond)),
+				fmt.Sprintf("%.3f", float64(bucket.P50Duration)/float64(time.Millisecond)),
+				fmt.Sprintf("%.3f", float64(bucket.P90Duration)/float64(time.Millisecond)),
+				fmt.Sprintf("%.3f", float64(bucket.P95Duration)/float64(time.Millisecond)),
+				fmt.Sprintf("%.3f", float64(bucket.P99Duration)/float64(time.Millisecond)),
+				fmt.Sprintf("%.3f", float64(bucket.MaxDuration)/float64(time.Millisecond)),
+			}
+			for _, code := range codes {
+				line = append(line, fmt.Sprintf("%d", bucket.StatusCodes[code]))
+			}
+			err := writer.Write(line)
+			if err != nil {
+				return fmt.Errorf("failed to write time series to %s: %w", filename, err)
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("failed to write time series to %s: %w", filename, err)
+		}
+		fmt.Println("finished!")
+	}
+	return nil
+}
+
+// writeHTMLReport writes an HTML report of the test, if requested
+func writeHTMLReport(title string, settings []pewpew.ReportSetting, targets []pewpew.Target, targetRequestStats [][]pewpew.RequestStat) error {
+	filename := viper.GetString("output-html")
+	if filename == "" {
+		return nil
+	}
+	interval, err := timeSeriesInterval()
+	if err != nil {
+		return err
+	}
+	fmt.Print("Writing HTML report to: " + filename + " ...")
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report to %s: %w", filename, err)
+	}
+	defer file.Close()
+	err = pewpew.WriteHTMLReport(file, pewpew.Report{
+		Title:              title,
+		Settings:           settings,
+		Targets:            targets,
+		TargetRequestStats: targetRequestStats,
+		Interval:           interval,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report to %s: %w", filename, err)
+	}
+	fmt.Println("finished!")
+	return nil
+}
@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+)
+
+// resultsVersion is the version of the resultRecord schema. Increase it when
+// a field is removed or its meaning changes, so old files aren't misread.
+const resultsVersion = 1
+
+// resultRecord is one request, as exported to every output file format.
+// Unlike RequestStat, every field can be written out and read back in.
+type resultRecord struct {
+	Version int `json:"version" xml:"version"`
+	//number of the target, starting at 1, same as the summaries
+	Target     int    `json:"target" xml:"target"`
+	Proto      string `json:"proto" xml:"proto"`
+	URL        string `json:"url" xml:"url"`
+	Method     string `json:"method" xml:"method"`
+	StatusCode int    `json:"statusCode" xml:"statusCode"`
+	Error      string `json:"error" xml:"error"`
+	//class of Error, from pewpew.ClassifyError
+	ErrorKind     string   `json:"errorKind" xml:"errorKind"`
+	CheckFailures []string `json:"checkFailures" xml:"checkFailure"`
+	Dropped       bool     `json:"dropped" xml:"dropped"`
+	Stage         int      `json:"stage" xml:"stage"`
+
+	StartTime         time.Time `json:"startTime" xml:"startTime"`
+	EndTime           time.Time `json:"endTime" xml:"endTime"`
+	IntendedStartTime time.Time `json:"intendedStartTime" xml:"intendedStartTime"`
+
+	//durations are in nanoseconds
+	Duration         time.Duration `json:"duration" xml:"duration"`
+	ResponseTime     time.Duration `json:"responseTime" xml:"responseTime"`
+	DNSDuration      time.Duration `json:"dnsDuration" xml:"dnsDuration"`
+	ConnectDuration  time.Duration `json:"connectDuration" xml:"connectDuration"`
+	TLSDuration      time.Duration `json:"tlsDuration" xml:"tlsDuration"`
+	TimeToFirstByte  time.Duration `json:"timeToFirstByte" xml:"timeToFirstByte"`
+	TransferDuration time.Duration `json:"transferDuration" xml:"transferDuration"`
+
+	DataTransferred int `json:"dataTransferred" xml:"dataTransferred"` //bytes
+}
+
+// xmlResults is the root element of XML results
+type xmlResults struct {
+	XMLName xml.Name       `xml:"results"`
+	Results []resultRecord `xml:"result"`
+}
+
+// csvHeader is the first row of CSV results, naming each column of resultRecord.csvRow
+var csvHeader = []string{
+	"version", "target", "proto", "url", "method", "statusCode", "error", "errorKind",
+	"checkFailures", "dropped", "stage", "startTime", "endTime", "intendedStartTime",
+	"duration", "responseTime", "dnsDuration", "connectDuration", "tlsDuration",
+	"timeToFirstByte", "transferDuration", "dataTransferred",
+}
+
+// recordedError is a request error read back from saved results
+type recordedError struct {
+	message string
+	kind    string
+}
+
+func (e *recordedError) Error() string {
+	return e.message
+}
+
+// newResultRecord creates the record of stat, from the target at index target
+func newResultRecord(target int, stat pewpew.RequestStat) resultRecord {
+	r := resultRecord{
+		Version:           resultsVersion,
+		Target:            target + 1,
+		Proto:             stat.Proto,
+		URL:               stat.URL,
+		Method:            stat.Method,
+		StatusCode:        stat.StatusCode,
+		CheckFailures:     stat.CheckFailures,
+		Dropped:           stat.Dropped,
+		Stage:             stat.Stage,
+		StartTime:         stat.StartTime,
+		EndTime:           stat.EndTime,
+		IntendedStartTime: stat.IntendedStartTime,
+		Duration:          stat.Duration,
+		ResponseTime:      stat.ResponseTime,
+		DNSDuration:       stat.DNSDuration,
+		ConnectDuration:   stat.ConnectDuration,
+		TLSDuration:       stat.TLSDuration,
+		TimeToFirstByte:   stat.TimeToFirstByte,
+		TransferDuration:  stat.TransferDuration,
+		DataTransferred:   stat.DataTransferred,
+	}
+	if stat.Error != nil {
+		r.Error = stat.Error.Error()
+		//keep the kind of errors read back in, since the message alone can't be classified
+		var recorded *recordedError
+		if errors.As(stat.Error, &recorded) {
+			r.ErrorKind = recorded.kind
+		} else {
+			r.ErrorKind = pewpew.ClassifyError(stat.Error)
+		}
+	}
+	return r
+}
+
+// requestStat converts the record back into a RequestStat
+func (r resultRecord) requestStat() pewpew.RequestStat {
+	stat := pewpew.RequestStat{
+		Proto:             r.Proto,
+		URL:               r.URL,
+		Method:            r.Method,
+		StatusCode:        r.StatusCode,
+		CheckFailures:     r.CheckFailures,
+		Dropped:           r.Dropped,
+		Stage:             r.Stage,
+		StartTime:         r.StartTime,
+		EndTime:           r.EndTime,
+		IntendedStartTime: r.IntendedStartTime,
+		Duration:          r.Duration,
+		ResponseTime:      r.ResponseTime,
+		DNSDuration:       r.DNSDuration,
+		ConnectDuration:   r.ConnectDuration,
+		TLSDuration:       r.TLSDuration,
+		TimeToFirstByte:   r.TimeToFirstByte,
+		TransferDuration:  r.TransferDuration,
+		DataTransferred:   r.DataTransferred,
+	}
+	if r.Error != "" {
+		stat.Error = &recordedError{message: r.Error, kind: r.ErrorKind}
+	}
+	return stat
+}
+
+// toResultRecords creates the records of every target's RequestStats, in target order
+func toResultRecords(targetRequestStats [][]pewpew.RequestStat) []resultRecord {
+	records := []resultRecord{}
+	for idx, stats := range targetRequestStats {
+		for _, stat := range stats {
+			records = append(records, newResultRecord(idx, stat))
+		}
+	}
+	return records
+}
+
+// fromResultRecords groups records back into the RequestStats of each target
+func fromResultRecords(records []resultRecord) [][]pewpew.RequestStat {
+	targetCount := 0
+	for _, r := range records {
+		if r.Target > targetCount {
+			targetCount = r.Target
+		}
+	}
+	targetRequestStats := make([][]pewpew.RequestStat, targetCount)
+	for _, r := range records {
+		targetRequestStats[r.Target-1] = append(targetRequestStats[r.Target-1], r.requestStat())
+	}
+	return targetRequestStats
+}
+
+// csvRow is the record as a row of CSV, in the order of csvHeader
+func (r resultRecord) csvRow() []string {
+	return []string{
+		strconv.Itoa(r.Version),
+		strconv.Itoa(r.Target),
+		r.Proto,
+		r.URL,
+		r.Method,
+		strconv.Itoa(r.StatusCode),
+		r.Error,
+		r.ErrorKind,
+		strings.Join(r.CheckFailures, "\n"),
+		strconv.FormatBool(r.Dropped),
+		strconv.Itoa(r.Stage),
+		r.StartTime.Format(time.RFC3339Nano),
+		r.EndTime.Format(time.RFC3339Nano),
+		r.IntendedStartTime.Format(time.RFC3339Nano),
+		strconv.FormatInt(int64(r.Duration), 10),
+		strconv.FormatInt(int64(r.ResponseTime), 10),
+		strconv.FormatInt(int64(r.DNSDuration), 10),
+		strconv.FormatInt(int64(r.ConnectDuration), 10),
+		strconv.FormatInt(int64(r.TLSDuration), 10),
+		strconv.FormatInt(int64(r.TimeToFirstByte), 10),
+		strconv.FormatInt(int64(r.TransferDuration), 10),
+		strconv.Itoa(r.DataTransferred),
+	}
+}
+
+// parseCSVRow reads a record from a row of CSV in the order of csvHeader
+func parseCSVRow(row []string) (resultRecord, error) {
+	r := resultRecord{}
+	if len(row) != len(csvHeader) {
+		return r, fmt.Errorf("got %d columns, wanted %d", len(row), len(csvHeader))
+	}
+	var errs []error
+	atoi := func(s string) int {
+		i, err := strconv.Atoi(s)
+		errs = append(errs, err)
+		return i
+	}
+	parseTime := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		errs = append(errs, err)
+		return t
+	}
+	parseDuration := func(s string) time.Duration {
+		d, err := strconv.ParseInt(s, 10, 64)
+		errs = append(errs, err)
+		return time.Duration(d)
+	}
+	r.Version = atoi(row[0])
+	r.Target = atoi(row[1])
+	r.Proto = row[2]
+	r.URL = row[3]
+	r.Method = row[4]
+	r.StatusCode = atoi(row[5])
+	r.Error = row[6]
+	r.ErrorKind = row[7]
+	if row[8] != "" {
+		r.CheckFailures = strings.Split(row[8], "\n")
+	}
+	dropped, err := strconv.ParseBool(row[9])
+	errs = append(errs, err)
+	r.Dropped = dropped
+	r.Stage = atoi(row[10])
+	r.StartTime = parseTime(row[11])
+	r.EndTime = parseTime(row[12])
+	r.IntendedStartTime = parseTime(row[13])
+	r.Duration = parseDuration(row[14])
+	r.ResponseTime = parseDuration(row[15])
+	r.DNSDuration = parseDuration(row[16])
+	r.ConnectDuration = parseDuration(row[17])
+	r.TLSDuration = parseDuration(row[18])
+	r.TimeToFirstByte = parseDuration(row[19])
+	r.TransferDuration = parseDuration(row[20])
+	r.DataTransferred = atoi(row[21])
+	for _, err := range errs {
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// writeResultsJSON writes records as a JSON array
+func writeResultsJSON(w io.Writer, records []resultRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(records)
+}
+
+// writeResultsCSV writes records as CSV with a header row
+func writeResultsCSV(w io.Writer, records []resultRecord) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		err := writer.Write(r.csvRow())
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeResultsXML writes records as XML
+func writeResultsXML(w io.Writer, records []resultRecord) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "    ")
+	return encoder.Encode(xmlResults{Results: records})
+}
+
+// writeResultsJSONL writes records as JSON Lines, one record per line
+func writeResultsJSONL(w io.Writer, records []resultRecord) error {
+	encoder := json.NewEncoder(w)
+	for _, r := range records {
+		err := encoder.Encode(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeResultsFile writes records to filename in the given format
+func writeResultsFile(filename string, write func(io.Writer, []resultRecord) error, records []resultRecord) error {
+	fmt.Print("Writing full result data to: " + filename + " ...")
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to write full result data to %s: %w", filename, err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	err = write(writer, records)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write full result data to %s: %w", filename, err)
+	}
+	fmt.Println("finished!")
+	return nil
+}
+
+// readResultsFile reads records previously written by pewpew. The format is
+// picked by the file extension: .json, .jsonl, .csv, or .xml.
+func readResultsFile(filename string) ([]resultRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []resultRecord
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = json.NewDecoder(file).Decode(&records)
+	case ".jsonl", ".ndjson":
+		decoder := json.NewDecoder(file)
+		for {
+			var r resultRecord
+			err = decoder.Decode(&r)
+			if err != nil {
+				break
+			}
+			records = append(records, r)
+		}
+		if err == io.EOF {
+			err = nil
+		}
+	case ".csv":
+		records, err = readResultsCSV(file)
+	case ".xml":
+		var results xmlResults
+		err = xml.NewDecoder(file).Decode(&results)
+		records = results.Results
+	default:
+		return nil, fmt.Errorf("unknown results format of %s, must be .json, .jsonl, .csv, or .xml", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results from %s: %w", filename, err)
+	}
+	for i, r := range records {
+		if r.Version != resultsVersion {
+			return nil, fmt.Errorf("failed to read results from %s: result %d is version %d, wanted %d", filename, i+1, r.Version, resultsVersion)
+		}
+		if r.Target < 1 {
+			return nil, fmt.Errorf("failed to read results from %s: result %d has invalid target %d", filename, i+1, r.Target)
+		}
+	}
+	return records, nil
+}
+
+func readResultsCSV(r io.Reader) ([]resultRecord, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+		return nil, errors.New("unexpected header row")
+	}
+	records := []resultRecord{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record, err := parseCSVRow(row)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+)
+
+// tempResultsFile returns the path of filename in a temporary directory
+func tempResultsFile(t *testing.T, filename string) string {
+	dir, err := ioutil.TempDir("", "pewpew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, filename)
+}
+
+// recordedStats are the RequestStats of two targets, covering every field
+func recordedStats() [][]pewpew.RequestStat {
+	start := time.Date(2020, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	return [][]pewpew.RequestStat{
+		{
+			{
+				Proto:             "HTTP/1.1",
+				URL:               "http://localhost/a?q=1,2",
+				Method:            "GET",
+				StartTime:         start,
+				EndTime:           start.Add(35 * time.Millisecond),
+				IntendedStartTime: start.Add(-5 * time.Millisecond),
+				Duration:          35 * time.Millisecond,
+				ResponseTime:      40 * time.Millisecond,
+				StatusCode:        200,
+				DataTransferred:   512,
+				DNSDuration:       1 * time.Millisecond,
+				ConnectDuration:   2 * time.Millisecond,
+				TLSDuration:       3 * time.Millisecond,
+				TimeToFirstByte:   20 * time.Millisecond,
+				TransferDuration:  9 * time.Millisecond,
+				Stage:             1,
+				CheckFailures:     []string{"status 200, wanted 201", `body does not match "ok"`},
+			},
+			{
+				URL:               "http://localhost/a",
+				Method:            "GET",
+				StartTime:         start.Add(time.Second),
+				IntendedStartTime: start.Add(time.Second),
+				Dropped:           true,
+			},
+		},
+		{
+			{
+				URL:               "http://missing.invalid/b",
+				Method:            "POST",
+				StartTime:         start,
+				EndTime:           start.Add(time.Millisecond),
+				IntendedStartTime: start,
+				Duration:          time.Millisecond,
+				ResponseTime:      time.Millisecond,
+				Error:             &net.DNSError{Err: "no such host", Name: "missing.invalid", IsNotFound: true},
+			},
+		},
+	}
+}
+
+// compareRequestStat reports every field of got that differs from want
+func compareRequestStat(t *testing.T, got, want pewpew.RequestStat) {
+	t.Helper()
+	for _, s := range []struct {
+		field     string
+		got, want string
+	}{
+		{"Proto", got.Proto, want.Proto},
+		{"URL", got.URL, want.URL},
+		{"Method", got.Method, want.Method},
+		{"StatusCode", strconv.Itoa(got.StatusCode), strconv.Itoa(want.StatusCode)},
+		{"DataTransferred", strconv.Itoa(got.DataTransferred), strconv.Itoa(want.DataTransferred)},
+		{"Stage", strconv.Itoa(got.Stage), strconv.Itoa(want.Stage)},
+		{"Dropped", strconv.FormatBool(got.Dropped), strconv.FormatBool(want.Dropped)},
+		{"CheckFailures", strings.Join(got.CheckFailures, "|"), strings.Join(want.CheckFailures, "|")},
+	} {
+		if s.got != s.want {
+			t.Errorf("got %s %q, wanted %q", s.field, s.got, s.want)
+		}
+	}
+	for _, d := range []struct {
+		field     string
+		got, want time.Duration
+	}{
+		{"Duration", got.Duration, want.Duration},
+		{"ResponseTime", got.ResponseTime, want.ResponseTime},
+		{"DNSDuration", got.DNSDuration, want.DNSDuration},
+		{"ConnectDuration", got.ConnectDuration, want.ConnectDuration},
+		{"TLSDuration", got.TLSDuration, want.TLSDuration},
+		{"TimeToFirstByte", got.TimeToFirstByte, want.TimeToFirstByte},
+		{"TransferDuration", got.TransferDuration, want.TransferDuration},
+	} {
+		if d.got != d.want {
+			t.Errorf("got %s %v, wanted %v", d.field, d.got, d.want)
+		}
+	}
+	for _, tm := range []struct {
+		field     string
+		got, want time.Time
+	}{
+		{"StartTime", got.StartTime, want.StartTime},
+		{"EndTime", got.EndTime, want.EndTime},
+		{"IntendedStartTime", got.IntendedStartTime, want.IntendedStartTime},
+	} {
+		if !tm.got.Equal(tm.want) {
+			t.Errorf("got %s %v, wanted %v", tm.field, tm.got, tm.want)
+		}
+	}
+	if (got.Error == nil) != (want.Error == nil) {
+		t.Fatalf("got error %v, wanted %v", got.Error, want.Error)
+	}
+	if want.Error == nil {
+		return
+	}
+	if got.Error.Error() != want.Error.Error() {
+		t.Errorf("got error %q, wanted %q", got.Error, want.Error)
+	}
+	//the kind of the original error can't be worked out from the message, so is kept with it
+	var recorded *recordedError
+	if !errors.As(got.Error, &recorded) {
+		t.Fatalf("got error of type %T, wanted *recordedError", got.Error)
+	}
+	if wantKind := pewpew.ClassifyError(want.Error); recorded.kind != wantKind {
+		t.Errorf("got error kind %q, wanted %q", recorded.kind, wantKind)
+	}
+}
+
+func TestResultsRoundTrip(t *testing.T) {
+	tests := []struct {
+		filename string
+		write    func(io.Writer, []resultRecord) error
+	}{
+		{"results.json", writeResultsJSON},
+		{"results.jsonl", writeResultsJSONL},
+		{"results.csv", writeResultsCSV},
+		{"results.xml", writeResultsXML},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+			want := recordedStats()
+			filename := tempResultsFile(t, tc.filename)
+			file, err := os.Create(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tc.write(file, toResultRecords(want))
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := readResultsFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fromResultRecords(records)
+			if len(got) != len(want) {
+				t.Fatalf("got %d targets, wanted %d", len(got), len(want))
+			}
+			for i := range want {
+				if len(got[i]) != len(want[i]) {
+					t.Fatalf("got %d requests for target %d, wanted %d", len(got[i]), i+1, len(want[i]))
+				}
+				for j := range want[i] {
+					compareRequestStat(t, got[i][j], want[i][j])
+				}
+			}
+
+			//writing read back results again keeps the error kind
+			rewritten := toResultRecords(got)
+			if rewritten[2].ErrorKind != "dns" {
+				t.Errorf("got rewritten error kind %q, wanted dns", rewritten[2].ErrorKind)
+			}
+		})
+	}
+}
+
+func TestReadResultsFileErrors(t *testing.T) {
+	record := newResultRecord(0, recordedStats()[0][0])
+	oldRecord := record
+	oldRecord.Version = resultsVersion + 1
+	noTarget := record
+	noTarget.Target = 0
+
+	tests := []struct {
+		name     string
+		filename string
+		records  []resultRecord
+		contents string
+		wantErr  string
+	}{
+		{name: "other version", filename: "results.json", records: []resultRecord{record, oldRecord}, wantErr: "result 2 is version 2, wanted 1"},
+		{name: "other version csv", filename: "results.csv", records: []resultRecord{oldRecord}, wantErr: "result 1 is version 2, wanted 1"},
+		{name: "missing version", filename: "results.jsonl", contents: `{"target": 1, "url": "http://localhost"}`, wantErr: "result 1 is version 0, wanted 1"},
+		{name: "invalid target", filename: "results.xml", records: []resultRecord{noTarget}, wantErr: "result 1 has invalid target 0"},
+		{name: "unknown header", filename: "results.csv", contents: "version,target\n1,1\n", wantErr: "unexpected header row"},
+		{name: "unknown format", filename: "results.txt", contents: "", wantErr: "unknown results format"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			filename := tempResultsFile(t, tc.filename)
+			file, err := os.Create(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.records != nil {
+				write := writeResultsJSON
+				switch filepath.Ext(tc.filename) {
+				case ".csv":
+					write = writeResultsCSV
+				case ".xml":
+					write = writeResultsXML
+				}
+				err = write(file, tc.records)
+			} else {
+				_, err = io.WriteString(file, tc.contents)
+			}
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = readResultsFile(filename)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, wanted %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCSVRow(t *testing.T) {
+	want := newResultRecord(1, recordedStats()[0][0])
+	tests := []struct {
+		name      string
+		modify    func([]string) []string
+		expectErr bool
+	}{
+		{name: "valid", modify: func(row []string) []string { return row }},
+		{name: "no check failures", modify: func(row []string) []string { row[8] = ""; return row }},
+		{name: "missing column", modify: func(row []string) []string { return row[1:] }, expectErr: true},
+		{name: "invalid version", modify: func(row []string) []string { row[0] = "v1"; return row }, expectErr: true},
+		{name: "invalid dropped", modify: func(row []string) []string { row[9] = "maybe"; return row }, expectErr: true},
+		{name: "invalid time", modify: func(row []string) []string { row[11] = "yesterday"; return row }, expectErr: true},
+		{name: "invalid duration", modify: func(row []string) []string { row[16] = "1ms"; return row }, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			row := tc.modify(want.csvRow())
+			got, err := parseCSVRow(row)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Version != want.Version || got.Target != want.Target || got.ErrorKind != want.ErrorKind ||
+				got.DNSDuration != want.DNSDuration || got.TimeToFirstByte != want.TimeToFirstByte ||
+				!got.IntendedStartTime.Equal(want.IntendedStartTime) {
+				t.Errorf("got %+v, wanted %+v", got, want)
+			}
+			if wantFailures := strings.Join(want.CheckFailures, "\n"); row[8] != "" && strings.Join(got.CheckFailures, "\n") != wantFailures {
+				t.Errorf("got check failures %q, wanted %q", got.CheckFailures, want.CheckFailures)
+			}
+			if row[8] == "" && got.CheckFailures != nil {
+				t.Errorf("got check failures %q, wanted none", got.CheckFailures)
+			}
+		})
+	}
+}
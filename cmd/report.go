@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report FILE",
+	Short: "Summarize results saved by a previous run",
+	Long: `Report reads results written with --output-json, --output-jsonl, --output-csv,
+or --output-xml, and prints their summary again. The results can be narrowed down
+with filters, checked against --threshold, and written out with any of the
+--output-* flags.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := newResultFilter()
+		if err != nil {
+			return err
+		}
+		err = validateOutputFlags()
+		if err != nil {
+			return err
+		}
+
+		//past this point, failures aren't from misuse of the command
+		cmd.SilenceUsage = true
+
+		records, err := readResultsFile(args[0])
+		if err != nil {
+			return err
+		}
+		records = filter.apply(records)
+		fmt.Printf("Read %d matching results from %s\n", len(records), args[0])
+
+		targetRequestStats := fromResultRecords(records)
+		targets := reportTargets(records, len(targetRequestStats))
+
+		globalStats := printSummaries(targets, targetRequestStats)
+
+		err = printTimeSeries(globalStats)
+		if err != nil {
+			return err
+		}
+
+		passed, err := printThresholds("Target", targets, targetRequestStats, globalStats, viper.GetStringSlice("thresholds"))
+		if err != nil {
+			return err
+		}
+
+		err = writeOutputFiles(targetRequestStats, globalStats)
+		if err != nil {
+			return err
+		}
+		if viper.GetString("output-jsonl") != "" {
+			err = writeResultsFile(viper.GetString("output-jsonl"), writeResultsJSONL, records)
+			if err != nil {
+				return err
+			}
+		}
+		settings := []pewpew.ReportSetting{{Name: "Results file", Value: args[0]}}
+		settings = append(settings, filter.settings...)
+		err = writeHTMLReport("Report", settings, targets, targetRequestStats)
+		if err != nil {
+			return err
+		}
+		if !passed {
+			return errors.New("one or more thresholds failed")
+		}
+		return nil
+	},
+}
+
+// resultFilter narrows down saved results to the ones matching the report flags
+type resultFilter struct {
+	targets map[int]bool
+	url     *regexp.Regexp
+	status  func(int) bool
+	//window of start times, as either offsets from the first result or absolute times
+	from, to       string
+	fromAbs, toAbs time.Time
+	fromOff, toOff time.Duration
+	//descriptions of the filters in use, for the HTML report
+	settings []pewpew.ReportSetting
+}
+
+// newResultFilter creates the filter from the report flags
+func newResultFilter() (*resultFilter, error) {
+	f := &resultFilter{}
+	if targets := viper.GetIntSlice("reportTargets"); len(targets) > 0 {
+		f.targets = make(map[int]bool)
+		names := make([]string, len(targets))
+		for i, target := range targets {
+			if target < 1 {
+				return nil, fmt.Errorf("invalid target %d, targets are numbered from 1", target)
+			}
+			f.targets[target] = true
+			names[i] = strconv.Itoa(target)
+		}
+		f.settings = append(f.settings, pewpew.ReportSetting{Name: "Targets", Value: strings.Join(names, ", ")})
+	}
+	if pattern := viper.GetString("reportURL"); pattern != "" {
+		url, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL pattern: %w", err)
+		}
+		f.url = url
+		f.settings = append(f.settings, pewpew.ReportSetting{Name: "URL pattern", Value: pattern})
+	}
+	if spec := viper.GetString("reportStatus"); spec != "" {
+		status, err := pewpew.ParseStatusCodes(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.status = status
+		f.settings = append(f.settings, pewpew.ReportSetting{Name: "Status", Value: spec})
+	}
+	var err error
+	f.from = viper.GetString("reportFrom")
+	f.fromAbs, f.fromOff, err = parseWindowBound(f.from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --from: %w", err)
+	}
+	f.to = viper.GetString("reportTo")
+	f.toAbs, f.toOff, err = parseWindowBound(f.to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --to: %w", err)
+	}
+	if f.from != "" || f.to != "" {
+		f.settings = append(f.settings, pewpew.ReportSetting{Name: "Time window", Value: fmt.Sprintf("from %s to %s", orDefault(f.from, "start"), orDefault(f.to, "end"))})
+	}
+	return f, nil
+}
+
+// parseWindowBound parses either an offset from the first result, like "30s",
+// or an RFC 3339 time. An empty bound is unbounded.
+func parseWindowBound(bound string) (time.Time, time.Duration, error) {
+	if bound == "" {
+		return time.Time{}, 0, nil
+	}
+	if offset, err := time.ParseDuration(bound); err == nil {
+		return time.Time{}, offset, nil
+	}
+	abs, err := time.Parse(time.RFC3339Nano, bound)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("%q must be an offset like 30s or a time like 2006-01-02T15:04:05Z", bound)
+	}
+	return abs, 0, nil
+}
+
+// apply returns the records matching every filter. Time window offsets are
+// from the earliest start time of all of the records.
+func (f *resultFilter) apply(records []resultRecord) []resultRecord {
+	var first time.Time
+	for _, r := range records {
+		if first.IsZero() || r.StartTime.Before(first) {
+			first = r.StartTime
+		}
+	}
+	from, to := f.fromAbs, f.toAbs
+	if f.from != "" && from.IsZero() {
+		from = first.Add(f.fromOff)
+	}
+	if f.to != "" && to.IsZero() {
+		to = first.Add(f.toOff)
+	}
+
+	matched := []resultRecord{}
+	for _, r := range records {
+		if f.targets != nil && !f.targets[r.Target] {
+			continue
+		}
+		if f.url != nil && !f.url.MatchString(r.URL) {
+			continue
+		}
+		if f.status != nil && (r.Error != "" || r.Dropped || !f.status(r.StatusCode)) {
+			continue
+		}
+		if !from.IsZero() && r.StartTime.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !r.StartTime.Before(to) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+	return matched
+}
+
+// reportTargets describes each target of the records, since the original
+// configuration isn't saved with them. Targets are described by their first
+// record, as regular expression targets have many URLs.
+func reportTargets(records []resultRecord, targetCount int) []pewpew.Target {
+	targets := make([]pewpew.Target, targetCount)
+	urls := make([]map[string]bool, targetCount)
+	for _, r := range records {
+		idx := r.Target - 1
+		if urls[idx] == nil {
+			urls[idx] = make(map[string]bool)
+			targets[idx] = pewpew.Target{URL: r.URL, Options: pewpew.TargetOptions{Method: r.Method}}
+		}
+		urls[idx][r.URL] = true
+	}
+	for idx := range targets {
+		if len(urls[idx]) > 1 {
+			targets[idx].URL += fmt.Sprintf(" (and %d other URLs)", len(urls[idx])-1)
+		}
+	}
+	return targets
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().IntSlice("target", []int{}, "Only include results of these target numbers, as numbered in the summary. Can be repeated or comma separated.")
+	reportCmd.Flags().String("url", "", "Only include results with a URL matching this regular expression.")
+	reportCmd.Flags().String("status", "", "Only include responses with these status codes or classes, eg. '200,5xx'. Excludes errors and dropped requests.")
+	reportCmd.Flags().String("from", "", "Only include requests started at or after this time, either an offset from the first request like '30s' or a time like '2006-01-02T15:04:05Z'.")
+	reportCmd.Flags().String("to", "", "Only include requests started before this time, either an offset from the first request like '5m' or a time like '2006-01-02T15:04:05Z'.")
+
+	flags := []struct {
+		key  string
+		flag string
+	}{
+		{"reportTargets", "target"},
+		{"reportURL", "url"},
+		{"reportStatus", "status"},
+		{"reportFrom", "from"},
+		{"reportTo", "to"},
+	}
+	for _, f := range flags {
+		err := viper.BindPFlag(f.key, reportCmd.Flags().Lookup(f.flag))
+		if err != nil {
+			fmt.Println("failed to configure flags")
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	}
+}
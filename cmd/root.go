@@ -5,6 +5,7 @@ import (
 	"os"
 	"runtime"
 
+	pewpew "github.com/bengadbois/pewpew/lib"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -46,6 +47,21 @@ func init() {
 	}
 
 	RootCmd.PersistentFlags().BoolP("regex", "r", false, "Interpret URLs as regular expressions.")
+	RootCmd.PersistentFlags().Bool("template", false, "Interpret URLs, --body, --headers, --cookies and --basic-auth as Go templates, executed for each request, eg. 'http://localhost/items/{{seq}}'. Functions: uuid, now, seq, randInt, randChoice, base64, env.")
+	RootCmd.PersistentFlags().String("requests", "", "JSON Lines file of requests to send, one object per line with url and optionally method, headers, body, weight, and timestamp. Added as a target alongside any URLs.")
+	RootCmd.PersistentFlags().String("requests-order", pewpew.RequestOrderRoundRobin, "Order to send the requests of --requests in: 'round-robin' repeats them in order, 'ordered' sends each once then stops, 'random' picks them at random by weight.")
+	RootCmd.PersistentFlags().String("data-file", "", "CSV file with a header line, or JSON file with an array of objects, of rows of data for the requests. URLs, --body, --headers, --cookies and --basic-auth are Go templates with a row as data, eg. 'localhost/users/{{.id}}'.")
+	RootCmd.PersistentFlags().String("data-order", pewpew.DataOrderSequential, "Order to use the rows of --data-file in: 'sequential' repeats them in order, 'random' picks one at random for each request, 'unique' uses each once then stops.")
+	RootCmd.PersistentFlags().String("har", "", "HAR file of recorded browser requests to send, each as its own target with the recorded method, headers, cookies and body. Added alongside any URLs.")
+	RootCmd.PersistentFlags().StringSlice("har-include-hosts", []string{}, "Only send --har requests to these hosts and their subdomains. Can be repeated or comma separated.")
+	RootCmd.PersistentFlags().StringSlice("har-exclude-hosts", []string{}, "Do not send --har requests to these hosts and their subdomains, eg. analytics. Can be repeated or comma separated.")
+	RootCmd.PersistentFlags().Bool("har-exclude-static", false, "Do not send --har requests for static assets: images, fonts, stylesheets, scripts and media.")
+	RootCmd.PersistentFlags().String("openapi", "", "OpenAPI 3 document, in JSON or YAML, to send a request to each operation of, each as its own target. Parameters and bodies are filled from examples, or generated from their schemas. Added alongside any URLs.")
+	RootCmd.PersistentFlags().String("openapi-server", "", "Base URL to send --openapi requests to, eg. 'http://localhost:8080/v1'. Defaults to the document's first server.")
+	RootCmd.PersistentFlags().StringSlice("openapi-operations", []string{}, "Only send --openapi requests to operations with these operationIds. Can be repeated or comma separated.")
+	RootCmd.PersistentFlags().StringSlice("openapi-tags", []string{}, "Only send --openapi requests to operations with any of these tags. Can be repeated or comma separated.")
+	RootCmd.PersistentFlags().StringSlice("openapi-methods", []string{}, "Only send --openapi requests to operations with these methods, eg. 'GET,HEAD'. Can be repeated or comma separated.")
+	RootCmd.PersistentFlags().String("from-curl", "", "curl command line to send the request of, eg. copied from a browser. Added as a target alongside any URLs, with curl's -X, -H, -d, -u, -b, -k, -L and --compressed options taking precedence.")
 	RootCmd.PersistentFlags().Bool("dns-prefetch", false, "Prefetch IP from hostname before making request, eliminating DNS fetching from timing.")
 	RootCmd.PersistentFlags().StringP("timeout", "t", "10s", "Maximum seconds to wait for response")
 	RootCmd.PersistentFlags().StringP("request-method", "X", "GET", "Request type. GET, HEAD, POST, PUT, etc.")
@@ -61,9 +77,22 @@ func init() {
 	RootCmd.PersistentFlags().Bool("follow-redirects", true, "Follow HTTP redirects.")
 	RootCmd.PersistentFlags().Bool("no-http2", false, "Disable HTTP2.")
 	RootCmd.PersistentFlags().Bool("enforce-ssl", false, "Enfore SSL certificate correctness.")
+	RootCmd.PersistentFlags().String("expect-status", "", "Comma separated status codes or classes responses must have, eg. '200,201,3xx'. Other responses are counted as failed checks.")
+	RootCmd.PersistentFlags().String("expect-headers", "", "Comma separated headers responses must have, eg. 'Content-Type:application/json, ETag'. NAME:VALUE requires the header to contain VALUE.")
+	RootCmd.PersistentFlags().String("expect-body", "", "Regular expression response bodies must match.")
+	RootCmd.PersistentFlags().String("reject-body", "", "Regular expression response bodies must not match.")
+	RootCmd.PersistentFlags().StringSlice("expect-json", []string{}, "JSON path response bodies must have, eg. 'data.items.0.id' or 'status=ok' to also check the value. Can be repeated.")
+	RootCmd.PersistentFlags().StringSlice("threshold", []string{}, "Pass/fail condition checked after the run, eg. 'p95 < 300ms' or 'error-rate < 1%'. Can be repeated or comma separated. Fails with a non-zero exit code if any are not met.")
 	RootCmd.PersistentFlags().String("output-json", "", "Path to file to write full data as JSON")
 	RootCmd.PersistentFlags().String("output-csv", "", "Path to file to write full data as CSV")
 	RootCmd.PersistentFlags().String("output-xml", "", "Path to file to write full data as XML")
+	RootCmd.PersistentFlags().String("output-jsonl", "", "Path to file to write each result to as JSON Lines as soon as it completes, so long runs use less memory to export and keep their results if stopped")
+	RootCmd.PersistentFlags().String("output-html", "", "Path to file to write a self-contained HTML report with charts")
+	RootCmd.PersistentFlags().String("metrics-addr", "", "Address to serve live Prometheus metrics on during the run, eg. ':9090'. Metrics are at /metrics.")
+	RootCmd.PersistentFlags().Bool("timeseries", false, "Print a table of throughput, errors, status codes and latency for each interval of the test.")
+	RootCmd.PersistentFlags().String("timeseries-interval", "1s", "Length of each interval of the time series.")
+	RootCmd.PersistentFlags().String("output-timeseries-csv", "", "Path to file to write the time series as CSV")
+	RootCmd.PersistentFlags().String("output-timeseries-json", "", "Path to file to write the time series as JSON")
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "Do not print while requests are running.")
 	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "Print extra troubleshooting info.")
 	RootCmd.PersistentFlags().Int("cpu", runtime.GOMAXPROCS(0), "Number of CPUs to use.")
@@ -74,4 +103,45 @@ func init() {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
+	//bind to the plural name so it lines up with the Thresholds config field
+	err = viper.BindPFlag("thresholds", RootCmd.PersistentFlags().Lookup("threshold"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	//bind the HAR and OpenAPI options to names that read well in a config file
+	flags := []struct {
+		key  string
+		flag string
+	}{
+		{"HARIncludeHosts", "har-include-hosts"},
+		{"HARExcludeHosts", "har-exclude-hosts"},
+		{"HARExcludeStatic", "har-exclude-static"},
+		{"OpenAPIServer", "openapi-server"},
+		{"OpenAPIOperations", "openapi-operations"},
+		{"OpenAPITags", "openapi-tags"},
+		{"OpenAPIMethods", "openapi-methods"},
+	}
+	for _, f := range flags {
+		err = viper.BindPFlag(f.key, RootCmd.PersistentFlags().Lookup(f.flag))
+		if err != nil {
+			fmt.Println("failed to configure flags")
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	}
+}
+
+// bindSharedFlags binds keys that more than one command has a flag for, such as
+// duration. A key can only be bound to one flag, so each of those commands binds
+// its own flags when it runs, giving every command the same flag precedence.
+func bindSharedFlags(cmd *cobra.Command, keys ...string) error {
+	for _, key := range keys {
+		err := viper.BindPFlag(key, cmd.Flags().Lookup(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
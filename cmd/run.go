@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+)
+
+// runObserved calls run with the Observers the output flags ask for, such as
+// the metrics server and the --output-jsonl file, and stops them once run
+// returns. run's context is cancelled on an interrupt, in which case
+// interrupted is true and the partial results are still worth summarizing.
+func runObserved(targets []pewpew.Target, run func(ctx context.Context, observers []pewpew.Observer) error) (interrupted bool, err error) {
+	observers, stopMetrics, err := startMetricsServer(targets)
+	if err != nil {
+		return false, err
+	}
+	defer stopMetrics()
+	jsonl, err := createJSONLWriter()
+	if err != nil {
+		return false, err
+	}
+	if jsonl != nil {
+		observers = append(observers, jsonl)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	runErr := run(ctx, observers)
+	closeErr := jsonl.close()
+	if runErr != nil && !checkInterrupted(runErr) {
+		if closeErr != nil {
+			return false, fmt.Errorf("%w, and %s", runErr, closeErr)
+		}
+		return false, runErr
+	}
+	return runErr != nil, closeErr
+}
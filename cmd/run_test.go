@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/viper"
+)
+
+// freeAddr is a local address with nothing listening on it
+func freeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func TestRunObserved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pewpew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	//the flags are global, so the cases can't run in parallel
+	defer viper.Set("metrics-addr", "")
+	defer viper.Set("output-jsonl", "")
+	targets := []pewpew.Target{{URL: "http://localhost"}}
+
+	tests := []struct {
+		name            string
+		jsonl           string
+		runErr          error
+		wantRun         bool
+		wantInterrupted bool
+		wantErr         bool
+	}{
+		{name: "finished", jsonl: filepath.Join(dir, "results.jsonl"), wantRun: true},
+		{name: "interrupted", jsonl: filepath.Join(dir, "results.jsonl"), runErr: context.Canceled, wantRun: true, wantInterrupted: true},
+		{name: "failed", runErr: errors.New("failed"), wantRun: true, wantErr: true},
+		{name: "jsonl file can't be created", jsonl: filepath.Join(dir, "missing", "results.jsonl"), wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			viper.Set("metrics-addr", addr)
+			viper.Set("output-jsonl", tc.jsonl)
+			ran := false
+			interrupted, err := runObserved(targets, func(ctx context.Context, observers []pewpew.Observer) error {
+				ran = true
+				//the metrics, and the JSONL file if set
+				want := 1
+				if tc.jsonl != "" {
+					want++
+				}
+				if len(observers) != want {
+					t.Errorf("got %d observers, wanted %d", len(observers), want)
+				}
+				return tc.runErr
+			})
+			if ran != tc.wantRun || interrupted != tc.wantInterrupted || (err != nil) != tc.wantErr {
+				t.Errorf("got ran %t, interrupted %t, error %v", ran, interrupted, err)
+			}
+			//the metrics server is stopped however the run ends
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				t.Fatalf("metrics server still running: %v", err)
+			}
+			listener.Close()
+		})
+	}
+}
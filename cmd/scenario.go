@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var scenarioCmd = &cobra.Command{
+	Use:   "scenario",
+	Short: "Run multi-step scenarios, such as login then create then fetch",
+	Long: `Scenario has each virtual user run through the Steps of the config file in
+order, over and over. Values extracted from a step's response are variables
+for the steps after it, used like {{.token}}. Each step is summarized on its
+own, along with the rate of iterations that completed every step.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindSharedFlags(cmd, "duration")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		scenarioCfg := pewpew.ScenarioConfig{}
+		err := viper.Unmarshal(&scenarioCfg)
+		if err != nil {
+			fmt.Println(err)
+			return errors.New("could not parse config file")
+		}
+
+		//global configs
+		scenarioCfg.Quiet = viper.GetBool("quiet")
+		scenarioCfg.Verbose = viper.GetBool("verbose")
+		scenarioCfg.Iterations = viper.GetInt("iterations")
+		scenarioCfg.Duration = viper.GetInt("duration")
+		scenarioCfg.Users = viper.GetInt("users")
+		scenarioCfg.MinSuccessRate = viper.GetFloat64("MinSuccessRate")
+		if cmd.Flags().Changed("data-file") || scenarioCfg.DataFile == "" {
+			scenarioCfg.DataFile = viper.GetString("data-file")
+		}
+		if cmd.Flags().Changed("data-order") || scenarioCfg.DataOrder == "" {
+			scenarioCfg.DataOrder = viper.GetString("data-order")
+		}
+
+		if len(scenarioCfg.Steps) == 0 {
+			return errors.New("requires Steps in the config file")
+		}
+		configSteps, _ := viper.Get("steps").([]interface{})
+		for i := range scenarioCfg.Steps {
+			if i >= len(configSteps) {
+				return errors.New("could not parse Steps of config file")
+			}
+			t := &scenarioCfg.Steps[i].Target
+			//the data file is the scenario's, rather than each step's
+			dataFile, dataOrder := t.DataFile, t.DataOrder
+			stepMapVals, err := configTargetValues(t, configSteps[i])
+			if err != nil {
+				return err
+			}
+			applyGlobalOptions(t, stepMapVals)
+			t.DataFile, t.DataOrder = dataFile, dataOrder
+		}
+		targets := make([]pewpew.Target, len(scenarioCfg.Steps))
+		for i, step := range scenarioCfg.Steps {
+			targets[i] = step.Target
+		}
+
+		err = validateOutputFlags()
+		if err != nil {
+			return err
+		}
+
+		//past this point, failures aren't from misuse of the command
+		cmd.SilenceUsage = true
+
+		var stepRequestStats [][]pewpew.RequestStat
+		var iterations []pewpew.ScenarioIteration
+		interrupted, err := runObserved(targets, func(ctx context.Context, observers []pewpew.Observer) error {
+			scenarioCfg.Observers = append(scenarioCfg.Observers, observers...)
+			var runErr error
+			stepRequestStats, iterations, runErr = pewpew.RunScenarioContext(ctx, scenarioCfg, os.Stdout)
+			return runErr
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Print("\n----Summary----\n\n")
+		globalStats := []pewpew.RequestStat{}
+		for idx, step := range scenarioCfg.Steps {
+			globalStats = append(globalStats, stepRequestStats[idx]...)
+			//nothing to summarize, such as a step after one that always fails
+			if len(stepRequestStats[idx]) == 0 {
+				continue
+			}
+			fmt.Println("----" + pewpew.StepName(idx, step))
+			fmt.Println(pewpew.CreateTextSummary(pewpew.CreateRequestsStats(stepRequestStats[idx])))
+		}
+		fmt.Println("----Global----")
+		fmt.Println(pewpew.CreateTextSummary(pewpew.CreateRequestsStats(globalStats)))
+		fmt.Println("----Scenario----")
+		fmt.Println(pewpew.CreateScenarioSummary(scenarioCfg.Steps, iterations))
+
+		err = printTimeSeries(globalStats)
+		if err != nil {
+			return err
+		}
+
+		passed, err := printThresholds("Step", targets, stepRequestStats, globalStats, scenarioCfg.Thresholds)
+		if err != nil {
+			return err
+		}
+		successRate := pewpew.ScenarioSuccessRate(iterations)
+		successful := scenarioCfg.MinSuccessRate <= 0 || successRate >= scenarioCfg.MinSuccessRate
+		if !successful {
+			fmt.Printf("Success rate %.2f%% is below the minimum of %.2f%%\n", successRate, scenarioCfg.MinSuccessRate)
+		}
+
+		err = writeOutputFiles(stepRequestStats, globalStats)
+		if err != nil {
+			return err
+		}
+		settings := []pewpew.ReportSetting{}
+		if scenarioCfg.Iterations > 0 {
+			settings = append(settings, pewpew.ReportSetting{Name: "Iterations", Value: fmt.Sprintf("%d", scenarioCfg.Iterations)})
+		}
+		if scenarioCfg.Duration > 0 {
+			settings = append(settings, pewpew.ReportSetting{Name: "Duration", Value: fmt.Sprintf("%d seconds", scenarioCfg.Duration)})
+		}
+		settings = append(settings,
+			pewpew.ReportSetting{Name: "Users", Value: fmt.Sprintf("%d", scenarioCfg.Users)},
+			pewpew.ReportSetting{Name: "Steps", Value: fmt.Sprintf("%d", len(scenarioCfg.Steps))},
+			pewpew.ReportSetting{Name: "Success rate", Value: fmt.Sprintf("%.2f%%", successRate)})
+		err = writeHTMLReport("Scenario test", settings, targets, stepRequestStats)
+		if err != nil {
+			return err
+		}
+		if interrupted {
+			return errors.New("run was interrupted")
+		}
+		if !passed {
+			return errors.New("one or more thresholds failed")
+		}
+		if !successful {
+			return errors.New("success rate below minimum")
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(scenarioCmd)
+	scenarioCmd.Flags().IntP("iterations", "n", pewpew.DefaultCount, "Number of total times to run through the steps, across all users. 0 means no limit when --duration is set.")
+	err := viper.BindPFlag("iterations", scenarioCmd.Flags().Lookup("iterations"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	scenarioCmd.Flags().IntP("duration", "d", 0, "Number of seconds to keep starting iterations. With --iterations, stops at whichever is reached first. Set --iterations to 0 to only stop on duration.")
+
+	scenarioCmd.Flags().IntP("users", "u", pewpew.DefaultConcurrency, "Number of virtual users running through the steps at the same time.")
+	err = viper.BindPFlag("users", scenarioCmd.Flags().Lookup("users"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	scenarioCmd.Flags().Float64("min-success-rate", 0, "Lowest acceptable percentage of iterations that complete every step, eg. 99. Fails with a non-zero exit code if not met.")
+	err = viper.BindPFlag("MinSuccessRate", scenarioCmd.Flags().Lookup("min-success-rate"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
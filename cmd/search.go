@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search URL...",
+	Short: "Search for the highest rate that meets latency and error criteria",
+	Long: `Search runs a series of short benchmarks at different rates to find the highest
+rate where response time at the chosen percentile stays under --max-latency, the
+error rate stays under --max-error-rate, and the requests could be sent at close
+to that rate.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindSharedFlags(cmd, "duration", "arrival")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		searchCfg := pewpew.SearchConfig{}
+		err := viper.Unmarshal(&searchCfg)
+		if err != nil {
+			fmt.Println(err)
+			return errors.New("could not parse config file")
+		}
+
+		//global configs
+		searchCfg.Quiet = viper.GetBool("quiet")
+		searchCfg.Verbose = viper.GetBool("verbose")
+		searchCfg.Mode = viper.GetString("mode")
+		searchCfg.MinRPS = viper.GetInt("minRPS")
+		searchCfg.MaxRPS = viper.GetInt("maxRPS")
+		searchCfg.Step = viper.GetInt("step")
+		searchCfg.Duration = viper.GetInt("duration")
+		searchCfg.Arrival = viper.GetString("arrival")
+		searchCfg.Percentile = viper.GetFloat64("percentile")
+		searchCfg.MaxLatency = viper.GetString("maxLatency")
+		searchCfg.MaxErrorRate = viper.GetFloat64("maxErrorRate")
+
+		searchCfg.Targets, err = buildTargets(searchCfg.Targets, args)
+		if err != nil {
+			return err
+		}
+
+		result, err := pewpew.RunSearch(searchCfg, os.Stdout)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print("\n----Search Summary----\n")
+		fmt.Println(pewpew.CreateSearchSummary(searchCfg, result))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().String("mode", pewpew.DefaultSearchMode, "How to pick the rate of each probe: 'binary' halves the remaining range each probe, 'step' increases the rate by --step until a probe fails.")
+	err := viper.BindPFlag("mode", searchCmd.Flags().Lookup("mode"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	searchCmd.Flags().Int("min-rps", pewpew.DefaultMinRPS, "Lowest rate to probe.")
+	err = viper.BindPFlag("minRPS", searchCmd.Flags().Lookup("min-rps"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	searchCmd.Flags().Int("max-rps", pewpew.DefaultMaxRPS, "Highest rate to probe.")
+	err = viper.BindPFlag("maxRPS", searchCmd.Flags().Lookup("max-rps"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	searchCmd.Flags().Int("step", pewpew.DefaultStep, "Rate increase between probes in step mode, and how close to get before stopping in binary mode.")
+	err = viper.BindPFlag("step", searchCmd.Flags().Lookup("step"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	searchCmd.Flags().Int("duration", pewpew.DefaultProbeDuration, "Number of seconds to run each probe.")
+
+	searchCmd.Flags().String("arrival", pewpew.DefaultArrival, "How requests are spaced out within each second of a probe: 'burst', 'uniform', or 'poisson'.")
+
+	searchCmd.Flags().Float64("percentile", pewpew.DefaultPercentile, "Response time percentile that must stay under --max-latency, eg. 99 or 99.9.")
+	err = viper.BindPFlag("percentile", searchCmd.Flags().Lookup("percentile"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	searchCmd.Flags().String("max-latency", pewpew.DefaultMaxLatency, "Highest acceptable response time at --percentile, eg. '300ms'.")
+	err = viper.BindPFlag("maxLatency", searchCmd.Flags().Lookup("max-latency"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	searchCmd.Flags().Float64("max-error-rate", pewpew.DefaultMaxErrorRate, "Highest acceptable percentage of requests that fail or get a 5xx response.")
+	err = viper.BindPFlag("maxErrorRate", searchCmd.Flags().Lookup("max-error-rate"))
+	if err != nil {
+		fmt.Println("failed to configure flags")
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
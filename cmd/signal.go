@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContext creates a context that is cancelled on the first SIGINT or SIGTERM,
+// so a run can stop early and still report what it collected.
+// A second signal kills the process as usual.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// checkInterrupted reports whether err is from the run being interrupted,
+// in which case the partial results are still worth summarizing
+func checkInterrupted(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("\nInterrupted, summarizing the requests completed so far")
+		return true
+	}
+	return false
+}
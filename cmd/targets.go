@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/viper"
+)
+
+// buildTargets decides which Targets to test and fills in their options.
+// configTargets are the Targets read from the config file, if any.
+func buildTargets(configTargets []pewpew.Target, args []string) ([]pewpew.Target, error) {
+	//URLs are handled differently that other config options
+	//command line specifying URLs take higher precedence than config URLs
+
+	requestFile := viper.GetString("requests")
+	harFile := viper.GetString("har")
+	curlCommand := viper.GetString("from-curl")
+	openAPIFile := viper.GetString("openapi")
+
+	//check either set via config or command line
+	fromFlags := len(args) >= 1 || requestFile != "" || harFile != "" || curlCommand != "" || openAPIFile != ""
+	if len(configTargets) == 0 && !fromFlags {
+		return nil, errors.New("requires URL, --requests, --har, --from-curl, or --openapi")
+	}
+
+	//if URLs are set on command line, use that for Targets instead of config
+	if fromFlags {
+		targets := make([]pewpew.Target, len(args))
+		for i := range args {
+			targets[i].URL = args[i]
+		}
+		//a request file is one more target, described by its filename
+		if requestFile != "" {
+			targets = append(targets, pewpew.Target{
+				URL:          requestFile,
+				RequestFile:  requestFile,
+				RequestOrder: viper.GetString("requests-order"),
+			})
+		}
+		//each recorded HAR request and OpenAPI operation is one more target
+		importStart := len(targets)
+		var imported []pewpew.Target
+		if harFile != "" {
+			harTargets, err := pewpew.ReadHAR(harFile, pewpew.HARFilter{
+				IncludeHosts:  viper.GetStringSlice("HARIncludeHosts"),
+				ExcludeHosts:  viper.GetStringSlice("HARExcludeHosts"),
+				ExcludeStatic: viper.GetBool("HARExcludeStatic"),
+			})
+			if err != nil {
+				return nil, err
+			}
+			imported = append(imported, harTargets...)
+		}
+		if openAPIFile != "" {
+			openAPITargets, err := pewpew.ReadOpenAPI(openAPIFile, pewpew.OpenAPIOptions{
+				ServerURL:  viper.GetString("OpenAPIServer"),
+				Operations: viper.GetStringSlice("OpenAPIOperations"),
+				Tags:       viper.GetStringSlice("OpenAPITags"),
+				Methods:    viper.GetStringSlice("OpenAPIMethods"),
+			})
+			if err != nil {
+				return nil, err
+			}
+			imported = append(imported, openAPITargets...)
+		}
+		targets = append(targets, imported...)
+		//a curl command is one more target
+		curlIdx := -1
+		var curlTarget pewpew.Target
+		if curlCommand != "" {
+			var err error
+			curlTarget, err = pewpew.ParseCurl(curlCommand)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse --from-curl: %w", err)
+			}
+			curlIdx = len(targets)
+			targets = append(targets, curlTarget)
+		}
+		for i := range targets {
+			//use global configs instead of the config file's individual target settings
+			targets[i].RegexURL = viper.GetBool("regex")
+			targets[i].Template = viper.GetBool("template")
+			targets[i].DataFile = viper.GetString("data-file")
+			targets[i].DataOrder = viper.GetString("data-order")
+			targets[i].Options.DNSPrefetch = viper.GetBool("dns-prefetch")
+			targets[i].Options.Timeout = viper.GetString("timeout")
+			targets[i].Options.Method = viper.GetString("request-method")
+			targets[i].Options.Body = viper.GetString("body")
+			targets[i].Options.RegexBody = viper.GetBool("body-regex")
+			targets[i].Options.BodyFilename = viper.GetString("body-file")
+			targets[i].Options.Headers = viper.GetString("headers")
+			targets[i].Options.Cookies = viper.GetString("cookies")
+			targets[i].Options.UserAgent = viper.GetString("user-agent")
+			targets[i].Options.BasicAuth = viper.GetString("basic-auth")
+			targets[i].Options.Compress = viper.GetBool("compress")
+			targets[i].Options.KeepAlive = viper.GetBool("keepalive")
+			targets[i].Options.FollowRedirects = viper.GetBool("follow-redirects")
+			targets[i].Options.NoHTTP2 = viper.GetBool("no-http2")
+			targets[i].Options.EnforceSSL = viper.GetBool("enforce-ssl")
+			targets[i].Options.ExpectStatus = viper.GetString("expect-status")
+			targets[i].Options.ExpectHeaders = viper.GetString("expect-headers")
+			targets[i].Options.ExpectBodyRegex = viper.GetString("expect-body")
+			targets[i].Options.RejectBodyRegex = viper.GetString("reject-body")
+			targets[i].Options.ExpectJSON = viper.GetStringSlice("expect-json")
+		}
+		//imported targets send the request they describe
+		for i, request := range imported {
+			t := &targets[importStart+i]
+			t.RegexURL = request.RegexURL
+			t.Options.Method = request.Options.Method
+			t.Options.Body = request.Options.Body
+			t.Options.RegexBody = request.Options.RegexBody
+			t.Options.BodyFilename = ""
+			t.Options.RawHeaders = request.Options.RawHeaders
+		}
+		//the curl target sends the request the same way curl would
+		if curlIdx >= 0 {
+			t := &targets[curlIdx]
+			t.RegexURL = false
+			t.Options.Method = curlTarget.Options.Method
+			t.Options.Body = curlTarget.Options.Body
+			t.Options.RegexBody = false
+			t.Options.BodyFilename = curlTarget.Options.BodyFilename
+			t.Options.Headers = ""
+			t.Options.RawHeaders = curlTarget.Options.RawHeaders
+			t.Options.Cookies = curlTarget.Options.Cookies
+			t.Options.UserAgent = curlTarget.Options.UserAgent
+			t.Options.BasicAuth = curlTarget.Options.BasicAuth
+			t.Options.Compress = curlTarget.Options.Compress
+			t.Options.FollowRedirects = curlTarget.Options.FollowRedirects
+			t.Options.EnforceSSL = curlTarget.Options.EnforceSSL
+			t.Options.NoHTTP2 = t.Options.NoHTTP2 || curlTarget.Options.NoHTTP2
+			if curlTarget.Options.Timeout != "" {
+				t.Options.Timeout = curlTarget.Options.Timeout
+			}
+		}
+		return targets, nil
+	}
+
+	//set non-URL target settings
+	//walk through viper.Get() because that will show which were
+	//explictly set instead of guessing at zero-valued defaults
+	targets := configTargets
+	for i, target := range viper.Get("targets").([]interface{}) {
+		targetMapVals, err := configTargetValues(&targets[i], target)
+		if err != nil {
+			return nil, err
+		}
+		applyGlobalOptions(&targets[i], targetMapVals)
+	}
+	return targets, nil
+}
+
+// configTargetValues returns the settings a config file sets on a target.
+// Options nested under Options, the layout of older config files, are decoded
+// into target as well, with settings directly on the target taking precedence.
+func configTargetValues(target *pewpew.Target, v interface{}) (map[string]interface{}, error) {
+	targetMapVals := configMap(v)
+	nested, ok := targetMapVals["Options"]
+	if !ok {
+		return targetMapVals, nil
+	}
+	merged := make(map[string]interface{})
+	for key, val := range configMap(nested) {
+		merged[key] = val
+	}
+	for key, val := range targetMapVals {
+		if key != "Options" {
+			merged[key] = val
+		}
+	}
+	//viper lowercases the keys of the map it's given, so it gets a copy
+	settings := make(map[string]interface{}, len(merged))
+	for key, val := range merged {
+		settings[key] = val
+	}
+	options := viper.New()
+	if err := options.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("failed to parse target options: %w", err)
+	}
+	if err := options.Unmarshal(&target.Options); err != nil {
+		return nil, fmt.Errorf("failed to parse target options: %w", err)
+	}
+	return merged, nil
+}
+
+// configMap is a map from a config file, which has interface{} keys when read from YAML
+func configMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	m := make(map[string]interface{})
+	if yamlMap, ok := v.(map[interface{}]interface{}); ok {
+		for key, val := range yamlMap {
+			m[fmt.Sprint(key)] = val
+		}
+	}
+	return m
+}
+
+// applyGlobalOptions sets each setting of a Target from the config file that
+// isn't in targetMapVals, the settings the config file sets, to the global value
+func applyGlobalOptions(target *pewpew.Target, targetMapVals map[string]interface{}) {
+	if _, set := targetMapVals["RegexURL"]; !set {
+		target.RegexURL = viper.GetBool("regex")
+	}
+	if _, set := targetMapVals["Template"]; !set {
+		target.Template = viper.GetBool("template")
+	}
+	if _, set := targetMapVals["DataFile"]; !set {
+		target.DataFile = viper.GetString("data-file")
+	}
+	if _, set := targetMapVals["DataOrder"]; !set {
+		target.DataOrder = viper.GetString("data-order")
+	}
+	if _, set := targetMapVals["RequestOrder"]; !set {
+		target.RequestOrder = viper.GetString("requests-order")
+	}
+	if _, set := targetMapVals["DNSPrefetch"]; !set {
+		target.Options.DNSPrefetch = viper.GetBool("dns-prefetch")
+	}
+	if _, set := targetMapVals["Timeout"]; !set {
+		target.Options.Timeout = viper.GetString("timeout")
+	}
+	if _, set := targetMapVals["Method"]; !set {
+		target.Options.Method = viper.GetString("request-method")
+	}
+	if _, set := targetMapVals["Body"]; !set {
+		target.Options.Body = viper.GetString("body")
+	}
+	if _, set := targetMapVals["RegexBody"]; !set {
+		target.Options.RegexBody = viper.GetBool("body-regex")
+	}
+	if _, set := targetMapVals["BodyFilename"]; !set {
+		target.Options.BodyFilename = viper.GetString("bodyFile")
+	}
+	if _, set := targetMapVals["Headers"]; !set {
+		target.Options.Headers = viper.GetString("headers")
+	}
+	if _, set := targetMapVals["Cookies"]; !set {
+		target.Options.Cookies = viper.GetString("cookies")
+	}
+	if _, set := targetMapVals["UserAgent"]; !set {
+		target.Options.UserAgent = viper.GetString("userAgent")
+	}
+	if _, set := targetMapVals["BasicAuth"]; !set {
+		target.Options.BasicAuth = viper.GetString("basicAuth")
+	}
+	if _, set := targetMapVals["Compress"]; !set {
+		target.Options.Compress = viper.GetBool("compress")
+	}
+	if _, set := targetMapVals["KeepAlive"]; !set {
+		target.Options.KeepAlive = viper.GetBool("keepalive")
+	}
+	if _, set := targetMapVals["FollowRedirects"]; !set {
+		target.Options.FollowRedirects = viper.GetBool("followredirects")
+	}
+	if _, set := targetMapVals["NoHTTP2"]; !set {
+		target.Options.NoHTTP2 = viper.GetBool("no-http2")
+	}
+	if _, set := targetMapVals["EnforceSSL"]; !set {
+		target.Options.EnforceSSL = viper.GetBool("enforce-ssl")
+	}
+	if _, set := targetMapVals["ExpectStatus"]; !set {
+		target.Options.ExpectStatus = viper.GetString("expect-status")
+	}
+	if _, set := targetMapVals["ExpectHeaders"]; !set {
+		target.Options.ExpectHeaders = viper.GetString("expect-headers")
+	}
+	if _, set := targetMapVals["ExpectBodyRegex"]; !set {
+		target.Options.ExpectBodyRegex = viper.GetString("expect-body")
+	}
+	if _, set := targetMapVals["RejectBodyRegex"]; !set {
+		target.Options.RejectBodyRegex = viper.GetString("reject-body")
+	}
+	if _, set := targetMapVals["ExpectJSON"]; !set {
+		target.Options.ExpectJSON = viper.GetStringSlice("expect-json")
+	}
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	pewpew "github.com/bengadbois/pewpew/lib"
+	"github.com/spf13/viper"
+)
+
+func TestBuildTargetsConfigLayouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		configType string
+		config     string
+		wantMethod string
+	}{
+		{
+			name:       "options on the target",
+			configType: "json",
+			config:     `{"Targets": [{"URL": "http://localhost/a", "Method": "POST", "Timeout": "3s", "Headers": "X-A:1"}]}`,
+			wantMethod: "POST",
+		},
+		{
+			name:       "options nested under Options",
+			configType: "json",
+			config:     `{"Targets": [{"URL": "http://localhost/a", "Options": {"Method": "POST", "Timeout": "3s", "Headers": "X-A:1"}}]}`,
+			wantMethod: "POST",
+		},
+		{
+			name:       "options nested under Options in yaml",
+			configType: "yaml",
+			config:     "Targets:\n  - URL: http://localhost/a\n    Options:\n      Method: POST\n      Timeout: 3s\n      Headers: X-A:1\n",
+			wantMethod: "POST",
+		},
+		{
+			name:       "options on the target take precedence",
+			configType: "json",
+			config:     `{"Targets": [{"URL": "http://localhost/a", "Method": "PUT", "Options": {"Method": "POST", "Timeout": "3s", "Headers": "X-A:1"}}]}`,
+			wantMethod: "PUT",
+		},
+	}
+	//the config is global, so the cases can't run in parallel
+	defer resetConfig(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.SetConfigType(tc.configType)
+			if err := viper.ReadConfig(strings.NewReader(tc.config)); err != nil {
+				t.Fatal(err)
+			}
+			var cfg pewpew.StressConfig
+			if err := viper.Unmarshal(&cfg); err != nil {
+				t.Fatal(err)
+			}
+			targets, err := buildTargets(cfg.Targets, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(targets) != 1 {
+				t.Fatalf("got %d targets, wanted 1", len(targets))
+			}
+			opts := targets[0].Options
+			if targets[0].URL != "http://localhost/a" || opts.Method != tc.wantMethod || opts.Timeout != "3s" || opts.Headers != "X-A:1" {
+				t.Errorf("got target %s with options %+v", targets[0].URL, opts)
+			}
+			//options the config doesn't set come from the flags
+			if !opts.KeepAlive {
+				t.Error("got KeepAlive false, wanted the flag's default of true")
+			}
+		})
+	}
+}
+
+// resetConfig clears the config file read by a test
+func resetConfig(t *testing.T) {
+	viper.SetConfigType("json")
+	if err := viper.ReadConfig(strings.NewReader("{}")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exampleYAML is the example config file, as YAML
+const exampleYAML = `Count: 15
+Concurrency: 4
+Compress: true
+Timeout: 1.75s
+Headers: Accept-Encoding:gzip
+Targets:
+  - URL: http://127.0.0.1/home
+  - URL: https://127.0.0.1/api/user
+    Method: POST
+    Body: '{"username": "newuser1", "email": "newuser1@domain.com"}'
+    Headers: Accept-Encoding:gzip, Content-Type:application/json
+    Cookies: data=123; session=456
+    Compress: true
+    Timeout: 500ms
+  - URL: https://127\.0\.0\.1/api/user/[0-9]{1,4}
+    RegexURL: true
+`
+
+func TestBuildTargetsExampleConfigs(t *testing.T) {
+	//the layout of config files from before options could be nested under
+	//Options, which must keep meaning the same thing
+	tests := []struct {
+		name       string
+		configType string
+		filename   string
+		config     string
+	}{
+		{name: "json", configType: "json", filename: "../examples/pewpew.json"},
+		{name: "toml", configType: "toml", filename: "../examples/pewpew.toml"},
+		{name: "yaml", configType: "yaml", config: exampleYAML},
+	}
+	defer resetConfig(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			if tc.filename != "" {
+				data, err := ioutil.ReadFile(tc.filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				config = string(data)
+			}
+			viper.SetConfigType(tc.configType)
+			if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+				t.Fatal(err)
+			}
+			var cfg pewpew.StressConfig
+			if err := viper.Unmarshal(&cfg); err != nil {
+				t.Fatal(err)
+			}
+			targets, err := buildTargets(cfg.Targets, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(targets) != 3 {
+				t.Fatalf("got %d targets, wanted 3", len(targets))
+			}
+
+			//settings the target doesn't have come from the global ones
+			home := targets[0]
+			if home.URL != "http://127.0.0.1/home" || home.Options.Method != "GET" || home.Options.Timeout != "1.75s" ||
+				home.Options.Headers != "Accept-Encoding:gzip" || !home.Options.Compress || home.RegexURL {
+				t.Errorf("got target 1 %s with options %+v", home.URL, home.Options)
+			}
+			user := targets[1].Options
+			if user.Method != "POST" || user.Body != `{"username": "newuser1", "email": "newuser1@domain.com"}` ||
+				user.Headers != "Accept-Encoding:gzip, Content-Type:application/json" ||
+				user.Cookies != "data=123; session=456" || user.Timeout != "500ms" || !user.Compress {
+				t.Errorf("got target 2 options %+v", user)
+			}
+			//the JSON example doesn't escape the dots of its regex
+			regexURL := strings.Replace(targets[2].URL, `\.`, ".", -1)
+			if regexURL != "https://127.0.0.1/api/user/[0-9]{1,4}" || !targets[2].RegexURL || targets[2].Options.Method != "GET" {
+				t.Errorf("got target 3 %s with RegexURL %t", targets[2].URL, targets[2].RegexURL)
+			}
+		})
+	}
+}
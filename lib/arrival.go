@@ -0,0 +1,98 @@
+package pewpew
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Arrival modes control how requests are spaced out within each second of a benchmark
+const (
+	//ArrivalBurst sends all of a second's requests at once, at the start of the second
+	ArrivalBurst = "burst"
+	//ArrivalUniform spaces requests evenly, 1/RPS apart
+	ArrivalUniform = "uniform"
+	//ArrivalPoisson spaces requests randomly with exponentially distributed gaps
+	//averaging 1/RPS, similar to traffic from many independent users
+	ArrivalPoisson = "poisson"
+)
+
+// arrivalScheduler decides when each request of a benchmark is sent
+type arrivalScheduler interface {
+	//next returns how long after the start of the benchmark the next request
+	//should be sent, or false once the benchmark's duration is used up
+	next() (time.Duration, bool)
+}
+
+// newArrivalScheduler creates the arrivalScheduler for the arrival mode,
+// following the rate of the profile. An empty mode is treated as DefaultArrival.
+func newArrivalScheduler(arrival string, profile *loadProfile) (arrivalScheduler, error) {
+	switch arrival {
+	case "":
+		return newArrivalScheduler(DefaultArrival, profile)
+	case ArrivalBurst:
+		return &burstArrivals{profile: profile}, nil
+	case ArrivalUniform:
+		return &uniformArrivals{profile: profile}, nil
+	case ArrivalPoisson:
+		return &poissonArrivals{
+			rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+			profile: profile,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown arrival mode %q, must be one of %s, %s, %s", arrival, ArrivalBurst, ArrivalUniform, ArrivalPoisson)
+	}
+}
+
+type burstArrivals struct {
+	profile *loadProfile
+	//start of the second currently being sent
+	second time.Duration
+	//start of the following second
+	nextSecond time.Duration
+	//requests left to send in the current second
+	remaining int
+}
+
+func (a *burstArrivals) next() (time.Duration, bool) {
+	for a.remaining == 0 {
+		if a.nextSecond >= a.profile.duration() {
+			return 0, false
+		}
+		a.second = a.nextSecond
+		a.nextSecond += time.Second
+		//small epsilon so float error doesn't drop a request, e.g. 2.9999999 becoming 2
+		before := math.Floor(a.profile.expectedCount(a.second) + 1e-9)
+		after := math.Floor(a.profile.expectedCount(a.nextSecond) + 1e-9)
+		a.remaining = int(after - before)
+	}
+	a.remaining--
+	return a.second, true
+}
+
+type uniformArrivals struct {
+	profile *loadProfile
+	sent    int
+}
+
+func (a *uniformArrivals) next() (time.Duration, bool) {
+	offset, ok := a.profile.offsetOf(float64(a.sent))
+	if !ok {
+		return 0, false
+	}
+	a.sent++
+	return offset, true
+}
+
+type poissonArrivals struct {
+	rng     *rand.Rand
+	profile *loadProfile
+	//expected number of requests sent so far
+	count float64
+}
+
+func (a *poissonArrivals) next() (time.Duration, bool) {
+	a.count += a.rng.ExpFloat64()
+	return a.profile.offsetOf(a.count)
+}
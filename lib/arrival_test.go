@@ -0,0 +1,114 @@
+package pewpew
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewArrivalScheduler(t *testing.T) {
+	tests := []struct {
+		name      string
+		arrival   string
+		expectErr bool
+	}{
+		{name: "default", arrival: "", expectErr: false},
+		{name: "burst", arrival: ArrivalBurst, expectErr: false},
+		{name: "uniform", arrival: ArrivalUniform, expectErr: false},
+		{name: "poisson", arrival: ArrivalPoisson, expectErr: false},
+		{name: "unknown", arrival: "unknown", expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := newArrivalScheduler(tc.arrival, newLoadProfile([]Stage{{Duration: "1s", StartRPS: 10, EndRPS: 10}}))
+			if (err != nil) != tc.expectErr {
+				t.Errorf("got error: %t, wanted: %t", (err != nil), tc.expectErr)
+			}
+		})
+	}
+}
+
+// arrivalOffsets drains the scheduler
+func arrivalOffsets(a arrivalScheduler) []time.Duration {
+	offsets := []time.Duration{}
+	for {
+		offset, ok := a.next()
+		if !ok {
+			return offsets
+		}
+		offsets = append(offsets, offset)
+	}
+}
+
+func TestArrivalOffsets(t *testing.T) {
+	tests := []struct {
+		name    string
+		arrival string
+		stages  []Stage
+		want    []time.Duration
+	}{
+		{
+			name:    "burst",
+			arrival: ArrivalBurst,
+			stages:  []Stage{{Duration: "2s", StartRPS: 2, EndRPS: 2}},
+			want:    []time.Duration{0, 0, time.Second, time.Second},
+		},
+		{
+			name:    "uniform",
+			arrival: ArrivalUniform,
+			stages:  []Stage{{Duration: "1s", StartRPS: 4, EndRPS: 4}},
+			want:    []time.Duration{0, 250 * time.Millisecond, 500 * time.Millisecond, 750 * time.Millisecond},
+		},
+		{
+			name:    "burst ramp",
+			arrival: ArrivalBurst,
+			stages:  []Stage{{Duration: "2s", StartRPS: 0, EndRPS: 4}},
+			want:    []time.Duration{0, time.Second, time.Second, time.Second},
+		},
+		{
+			name:    "uniform multiple stages",
+			arrival: ArrivalUniform,
+			stages:  []Stage{{Duration: "1s", StartRPS: 2, EndRPS: 2}, {Duration: "1s", StartRPS: 0, EndRPS: 0}, {Duration: "1s", StartRPS: 1, EndRPS: 1}},
+			want:    []time.Duration{0, 500 * time.Millisecond, 2 * time.Second},
+		},
+		{
+			name:    "no requests",
+			arrival: ArrivalUniform,
+			stages:  []Stage{{Duration: "1s", StartRPS: 0, EndRPS: 0}},
+			want:    []time.Duration{},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			a, err := newArrivalScheduler(tc.arrival, newLoadProfile(tc.stages))
+			if err != nil {
+				t.Fatalf("failed to create scheduler: %s", err)
+			}
+			got := arrivalOffsets(a)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got offsets: %v, wanted: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPoissonArrivals(t *testing.T) {
+	a, err := newArrivalScheduler(ArrivalPoisson, newLoadProfile([]Stage{{Duration: "10s", StartRPS: 1000, EndRPS: 1000}}))
+	if err != nil {
+		t.Fatalf("failed to create scheduler: %s", err)
+	}
+	offsets := arrivalOffsets(a)
+	//expect about 10000 arrivals, with a generous margin for randomness
+	if len(offsets) < 9000 || len(offsets) > 11000 {
+		t.Errorf("got %d arrivals, wanted about 10000", len(offsets))
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] < offsets[i-1] {
+			t.Fatalf("got offset %s before previous offset %s", offsets[i], offsets[i-1])
+		}
+	}
+}
@@ -1,10 +1,11 @@
 package pewpew
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"sync"
 	"time"
 )
 
@@ -18,19 +19,41 @@ type (
 		RPS int
 		//Duration is the number of seconds to run the benchmark test
 		Duration int
-		Targets  []Target
+		//Stages describes how the rate changes over the benchmark test.
+		//When set, RPS and Duration are ignored.
+		Stages []Stage
+		//Arrival is how requests are spaced out within each second,
+		//one of ArrivalBurst, ArrivalUniform, or ArrivalPoisson
+		Arrival string
+		//MaxInFlight is the most requests that can be in flight at once for each Target.
+		//Requests due while at the limit are recorded as dropped instead of sent late.
+		//Zero means no limit.
+		MaxInFlight int
+		Targets     []Target
+		//Thresholds are pass/fail conditions checked against the summary of all Targets combined
+		Thresholds []string
+		//Observers are notified about each request as the test runs
+		Observers []Observer
 
 		//global target settings
 		Options TargetOptions
 	}
 )
 
+// scheduledRequest is when a benchmark request is supposed to be sent
+type scheduledRequest struct {
+	//index of the Stage it is part of
+	stage    int
+	intended time.Time
+}
+
 // NewBenchmarkConfig creates a new BenchmarkConfig
 // with package defaults
 func NewBenchmarkConfig() (b *BenchmarkConfig) {
 	b = &BenchmarkConfig{
 		RPS:      DefaultRPS,
 		Duration: DefaultDuration,
+		Arrival:  DefaultArrival,
 		Targets: []Target{
 			{
 				URL: DefaultURL,
@@ -49,6 +72,13 @@ func NewBenchmarkConfig() (b *BenchmarkConfig) {
 // RunBenchmark starts the benchmark tests with the provided BenchmarkConfig.
 // Throughout the test, data is sent to w, useful for live updates.
 func RunBenchmark(b BenchmarkConfig, w io.Writer) ([][]RequestStat, error) {
+	return RunBenchmarkContext(context.Background(), b, w)
+}
+
+// RunBenchmarkContext is RunBenchmark, but stops early when ctx is done.
+// No more requests are sent, requests in flight are aborted, and the
+// RequestStats of the requests completed so far are returned, along with ctx's error.
+func RunBenchmarkContext(ctx context.Context, b BenchmarkConfig, w io.Writer) ([][]RequestStat, error) {
 	if w == nil {
 		return nil, errors.New("nil writer")
 	}
@@ -57,15 +87,19 @@ func RunBenchmark(b BenchmarkConfig, w io.Writer) ([][]RequestStat, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 	targetCount := len(b.Targets)
+	profile := newLoadProfile(benchmarkStages(b))
 
 	//setup printer
 	p := printer{output: w}
 
 	//setup the queue of requests, one queue per target
-	requestQueues := make([](chan http.Request), targetCount)
-	errChans := make([](chan error), targetCount)
+	//the number of requests depends on the arrival mode, so keep generating
+	//them until the benchmark is over
+	queueCtx, stopQueues := context.WithCancel(ctx)
+	defer stopQueues()
+	requestQueues := make([](chan queuedRequest), targetCount)
 	for idx, target := range b.Targets {
-		requestQueue, err := createRequestQueue(b.RPS*b.Duration, target)
+		requestQueue, err := createRequestQueue(queueCtx, 0, target)
 		if err != nil {
 			return nil, err
 		}
@@ -79,61 +113,126 @@ func RunBenchmark(b BenchmarkConfig, w io.Writer) ([][]RequestStat, error) {
 	}
 
 	//when a target is finished, send all stats into this
-	targetStats := make(chan []RequestStat)
+	targetStats := make(chan targetResult)
 	for idx, target := range b.Targets {
-		go func(target Target, requestQueue chan http.Request, errChan chan error, targetStats chan []RequestStat) {
-			p.writeString(fmt.Sprintf("- Benchmarking %s at %d RPS, for %d seconds\n", target.URL, b.RPS, b.Duration))
+		go func(idx int, target Target, requestQueue chan queuedRequest, targetStats chan targetResult) {
+			if len(b.Stages) > 0 {
+				p.writeString(fmt.Sprintf("- Benchmarking %s in %d stages, for %s\n", target.URL, len(b.Stages), profile.duration()))
+			} else {
+				p.writeString(fmt.Sprintf("- Benchmarking %s at %d RPS, for %d seconds\n", target.URL, b.RPS, b.Duration))
+			}
 
 			requestStatChan := make(chan RequestStat) //workers communicate each requests' info
 
 			client := createClient(target)
 
-			ticker := time.NewTicker(1 * time.Second)
-			secondsLeft := b.Duration
+			//already validated
+			checks, _ := newResponseChecks(target.Options)
+			scheduler, _ := newArrivalScheduler(b.Arrival, profile)
+			sendRequest := func(sched scheduledRequest) {
+				queued, ok := <-requestQueue
+				if !ok {
+					//queue was stopped by ctx
+					return
+				}
+				notifyStarted(b.Observers, idx)
+				response, stat := queued.send(ctx, target, client, checks)
+				stat.Stage = sched.stage
+				//measure from when the request should have gone out, so any
+				//delay from the client falling behind isn't hidden
+				stat.IntendedStartTime = sched.intended
+				stat.ResponseTime = stat.EndTime.Sub(sched.intended)
+				notifyFinished(b.Observers, idx, stat)
+				if abortedByCancel(ctx, stat) {
+					return
+				}
+				if !b.Quiet {
+					p.printStat(stat)
+					if b.Verbose {
+						p.printVerbose(&queued.req, response)
+					}
+				}
+				requestStatChan <- stat
+			}
 			go func() {
+				var inFlight sync.WaitGroup
+
+				//with a limit, a fixed pool of workers sends the requests
+				var workerQueue chan scheduledRequest
+				if b.MaxInFlight > 0 {
+					workerQueue = make(chan scheduledRequest)
+					for i := 0; i < b.MaxInFlight; i++ {
+						inFlight.Add(1)
+						go func() {
+							defer inFlight.Done()
+							for sched := range workerQueue {
+								sendRequest(sched)
+							}
+						}()
+					}
+				}
+
+				start := time.Now()
+			schedule:
 				for {
-					<-ticker.C
-					secondsLeft--
-					if secondsLeft < 0 {
-						return
+					offset, ok := scheduler.next()
+					if !ok {
+						break
 					}
-					for i := 0; i < b.RPS; i++ {
-						//run all the requests at the start of the second
-						//note: this means it's a little bursty, not evenly
-						//distributed throughout the 1 second window
+					timer := time.NewTimer(time.Until(start.Add(offset)))
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+						break schedule
+					}
+					sched := scheduledRequest{stage: profile.stageAt(offset), intended: start.Add(offset)}
+					if workerQueue == nil {
+						inFlight.Add(1)
 						go func() {
-							req := <-requestQueue
-							response, stat := runRequest(req, client)
-							if !b.Quiet {
-								p.printStat(stat)
-								if b.Verbose {
-									p.printVerbose(&req, response)
-								}
-							}
-							requestStatChan <- stat
+							defer inFlight.Done()
+							sendRequest(sched)
 						}()
+						continue
+					}
+					select {
+					case workerQueue <- sched:
+					default:
+						//every worker is busy, so sending now would fall behind schedule
+						stat := RequestStat{
+							URL:               target.URL,
+							Method:            target.Options.Method,
+							StartTime:         sched.intended,
+							EndTime:           sched.intended,
+							Dropped:           true,
+							Stage:             sched.stage,
+							IntendedStartTime: sched.intended,
+						}
+						notifyFinished(b.Observers, idx, stat)
+						if !b.Quiet {
+							p.printStat(stat)
+						}
+						requestStatChan <- stat
 					}
 				}
+				if workerQueue != nil {
+					close(workerQueue)
+				}
+				inFlight.Wait()
+				close(requestStatChan)
 			}()
 
-			requestStats := make([]RequestStat, b.RPS*b.Duration)
-			requestsCompleteCount := 0
-			for {
-				stat := <-requestStatChan
-				requestStats[requestsCompleteCount] = stat
-				requestsCompleteCount++
-				if requestsCompleteCount == b.RPS*b.Duration {
-					//all requests are finished
-					break
-				}
+			requestStats := make([]RequestStat, 0, int(profile.expectedCount(profile.duration())))
+			for stat := range requestStatChan {
+				requestStats = append(requestStats, stat)
 			}
-			targetStats <- requestStats
-		}(target, requestQueues[idx], errChans[idx], targetStats)
+			targetStats <- targetResult{idx: idx, stats: requestStats}
+		}(idx, target, requestQueues[idx], targetStats)
 	}
 	targetRequestStats := make([][]RequestStat, targetCount)
 	targetDoneCount := 0
-	for reqStats := range targetStats {
-		targetRequestStats[targetDoneCount] = reqStats
+	for result := range targetStats {
+		targetRequestStats[result.idx] = result.stats
 		targetDoneCount++
 		if targetDoneCount == targetCount {
 			//all targets are finished
@@ -141,18 +240,55 @@ func RunBenchmark(b BenchmarkConfig, w io.Writer) ([][]RequestStat, error) {
 		}
 	}
 
-	return targetRequestStats, nil
+	return targetRequestStats, ctx.Err()
+}
+
+// benchmarkStages is the Stages of the benchmark, where a benchmark without
+// Stages is a single stage of RPS for Duration seconds
+func benchmarkStages(b BenchmarkConfig) []Stage {
+	if len(b.Stages) > 0 {
+		return b.Stages
+	}
+	return []Stage{{
+		Duration: fmt.Sprintf("%ds", b.Duration),
+		StartRPS: b.RPS,
+		EndRPS:   b.RPS,
+	}}
 }
 
 func validateBenchmarkConfig(b BenchmarkConfig) error {
 	if len(b.Targets) == 0 {
 		return errors.New("zero targets")
 	}
-	if b.Duration <= 0 {
-		return errors.New("duration must be greater than zero")
+	if len(b.Stages) > 0 {
+		anyRequests := false
+		for _, stage := range b.Stages {
+			if err := validateStage(stage); err != nil {
+				return err
+			}
+			if stage.StartRPS > 0 || stage.EndRPS > 0 {
+				anyRequests = true
+			}
+		}
+		if !anyRequests {
+			return errors.New("at least one stage must have RPS greater than zero")
+		}
+	} else {
+		if b.Duration <= 0 {
+			return errors.New("duration must be greater than zero")
+		}
+		if b.RPS <= 0 {
+			return errors.New("RPS must be greater than zero")
+		}
+	}
+	if _, err := newArrivalScheduler(b.Arrival, newLoadProfile(benchmarkStages(b))); err != nil {
+		return err
+	}
+	if b.MaxInFlight < 0 {
+		return errors.New("max in flight cannot be negative")
 	}
-	if b.RPS <= 0 {
-		return errors.New("RPS must be greater than zero")
+	if err := validateThresholds(b.Thresholds); err != nil {
+		return err
 	}
 
 	for _, target := range b.Targets {
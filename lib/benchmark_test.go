@@ -1,9 +1,14 @@
 package pewpew
 
 import (
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestRunBenchmark(t *testing.T) {
@@ -261,6 +266,61 @@ func TestRunBenchmark(t *testing.T) {
 			writer:    ioutil.Discard,
 			expectErr: false,
 		},
+		{
+			name: "uniform arrival",
+			benchmarkConfig: BenchmarkConfig{
+				RPS:      2,
+				Duration: 1,
+				Arrival:  ArrivalUniform,
+				Targets: []Target{
+					{
+						URL: "http://localhost",
+						Options: TargetOptions{
+							Method: "GET",
+						},
+					},
+				},
+			},
+			writer:    ioutil.Discard,
+			expectErr: false,
+		},
+		{
+			name: "poisson arrival",
+			benchmarkConfig: BenchmarkConfig{
+				RPS:      2,
+				Duration: 1,
+				Arrival:  ArrivalPoisson,
+				Targets: []Target{
+					{
+						URL: "http://localhost",
+						Options: TargetOptions{
+							Method: "GET",
+						},
+					},
+				},
+			},
+			writer:    ioutil.Discard,
+			expectErr: false,
+		},
+		{
+			name: "stages",
+			benchmarkConfig: BenchmarkConfig{
+				Stages: []Stage{
+					{Duration: "1s", StartRPS: 0, EndRPS: 2},
+					{Duration: "1s", StartRPS: 2, EndRPS: 2},
+				},
+				Targets: []Target{
+					{
+						URL: "http://localhost",
+						Options: TargetOptions{
+							Method: "GET",
+						},
+					},
+				},
+			},
+			writer:    ioutil.Discard,
+			expectErr: false,
+		},
 		{
 			name:            "BenchmarkConfig constructor",
 			benchmarkConfig: *NewBenchmarkConfig(),
@@ -280,6 +340,91 @@ func TestRunBenchmark(t *testing.T) {
 	}
 }
 
+func TestRunBenchmarkContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	b := BenchmarkConfig{
+		RPS:      20,
+		Duration: 10,
+		Arrival:  ArrivalUniform,
+		Quiet:    true,
+		Targets: []Target{
+			{
+				URL: server.URL,
+				Options: TargetOptions{
+					Method: "GET",
+				},
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+	start := time.Now()
+	stats, err := RunBenchmarkContext(ctx, b, ioutil.Discard)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error: %v, wanted: %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s to stop after being cancelled", elapsed)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("got stats for %d targets, wanted 1", len(stats))
+	}
+	if len(stats[0]) == 0 || len(stats[0]) >= b.RPS*b.Duration {
+		t.Errorf("got %d partial results, wanted some but fewer than %d", len(stats[0]), b.RPS*b.Duration)
+	}
+}
+
+func TestRunBenchmarkMaxInFlight(t *testing.T) {
+	//each request takes far longer than the gap between requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	b := BenchmarkConfig{
+		RPS:         20,
+		Duration:    1,
+		Arrival:     ArrivalUniform,
+		MaxInFlight: 2,
+		Quiet:       true,
+		Targets: []Target{
+			{
+				URL: server.URL,
+				Options: TargetOptions{
+					Method: "GET",
+				},
+			},
+		},
+	}
+	stats, err := RunBenchmark(b, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(stats[0]) != b.RPS*b.Duration {
+		t.Errorf("got %d results, wanted one for each of the %d scheduled requests", len(stats[0]), b.RPS*b.Duration)
+	}
+	sent, dropped := 0, 0
+	for _, stat := range stats[0] {
+		if stat.Dropped {
+			dropped++
+			continue
+		}
+		sent++
+		if stat.IntendedStartTime.IsZero() || stat.ResponseTime < stat.Duration {
+			t.Errorf("got response time %s from %s, wanted at least the duration %s", stat.ResponseTime, stat.IntendedStartTime, stat.Duration)
+		}
+	}
+	//at most 2 at a time for about a second, at 300ms each
+	if sent == 0 || sent > 10 {
+		t.Errorf("got %d sent requests, wanted between 1 and 10", sent)
+	}
+	if dropped == 0 {
+		t.Error("got no dropped requests")
+	}
+}
+
 func TestValidateBenchmarkConfig(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -393,6 +538,90 @@ func TestValidateBenchmarkConfig(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "stages without RPS and duration",
+			config: BenchmarkConfig{
+				Stages: []Stage{{Duration: "1m", StartRPS: 0, EndRPS: 100}},
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid stage",
+			config: BenchmarkConfig{
+				Stages: []Stage{{Duration: "forever", StartRPS: 0, EndRPS: 100}},
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "stages with zero RPS",
+			config: BenchmarkConfig{
+				Stages: []Stage{{Duration: "1m", StartRPS: 0, EndRPS: 0}},
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative max in flight",
+			config: BenchmarkConfig{
+				RPS:         DefaultRPS,
+				Duration:    DefaultDuration,
+				MaxInFlight: -1,
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "unknown arrival",
+			config: BenchmarkConfig{
+				RPS:      DefaultRPS,
+				Duration: DefaultDuration,
+				Arrival:  "unknown",
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
 		{
 			name:      "valid",
 			config:    *NewBenchmarkConfig(),
@@ -0,0 +1,174 @@
+package pewpew
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// responseChecks are the compiled response checks of a Target,
+// built from the Expect and Reject TargetOptions
+type responseChecks struct {
+	statusSpec string
+	status     func(int) bool
+	headers    []headerCheck
+	expectBody *regexp.Regexp
+	rejectBody *regexp.Regexp
+	json       []jsonCheck
+}
+
+type headerCheck struct {
+	name string
+	//value the header must contain, or empty if it only has to be present
+	value string
+}
+
+type jsonCheck struct {
+	path string
+	//value the path must equal, only checked if hasValue
+	value    string
+	hasValue bool
+}
+
+// newResponseChecks compiles the response checks of the options,
+// returning nil if there aren't any
+func newResponseChecks(opts TargetOptions) (*responseChecks, error) {
+	if opts.ExpectStatus == "" && opts.ExpectHeaders == "" && opts.ExpectBodyRegex == "" &&
+		opts.RejectBodyRegex == "" && len(opts.ExpectJSON) == 0 {
+		return nil, nil
+	}
+	c := &responseChecks{}
+	if opts.ExpectStatus != "" {
+		c.statusSpec = opts.ExpectStatus
+		status, err := ParseStatusCodes(opts.ExpectStatus)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expected status: %w", err)
+		}
+		c.status = status
+	}
+	if opts.ExpectHeaders != "" {
+		for _, h := range strings.Split(opts.ExpectHeaders, ",") {
+			parts := strings.SplitN(h, ":", 2)
+			check := headerCheck{name: strings.TrimSpace(parts[0])}
+			if check.name == "" {
+				return nil, fmt.Errorf("invalid expected header %q, must be NAME or NAME:VALUE", h)
+			}
+			if len(parts) == 2 {
+				check.value = strings.TrimSpace(parts[1])
+			}
+			c.headers = append(c.headers, check)
+		}
+	}
+	var err error
+	if opts.ExpectBodyRegex != "" {
+		c.expectBody, err = regexp.Compile(opts.ExpectBodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile expected body regex: %w", err)
+		}
+	}
+	if opts.RejectBodyRegex != "" {
+		c.rejectBody, err = regexp.Compile(opts.RejectBodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile rejected body regex: %w", err)
+		}
+	}
+	for _, j := range opts.ExpectJSON {
+		parts := strings.SplitN(j, "=", 2)
+		check := jsonCheck{path: strings.TrimSpace(parts[0])}
+		if check.path == "" {
+			return nil, fmt.Errorf("invalid expected JSON %q, must be PATH or PATH=VALUE", j)
+		}
+		if len(parts) == 2 {
+			check.value = strings.TrimSpace(parts[1])
+			check.hasValue = true
+		}
+		c.json = append(c.json, check)
+	}
+	return c, nil
+}
+
+// check runs every check against the response and its body,
+// returning a description of each one that failed
+func (c *responseChecks) check(response *http.Response, body []byte) []string {
+	if c == nil {
+		return nil
+	}
+	var failures []string
+	if c.status != nil && !c.status(response.StatusCode) {
+		failures = append(failures, "status not one of "+c.statusSpec)
+	}
+	for _, h := range c.headers {
+		values, ok := response.Header[http.CanonicalHeaderKey(h.name)]
+		if !ok {
+			failures = append(failures, "missing header "+h.name)
+			continue
+		}
+		if h.value != "" && !strings.Contains(strings.Join(values, ", "), h.value) {
+			failures = append(failures, fmt.Sprintf("header %s does not contain %q", h.name, h.value))
+		}
+	}
+	if c.expectBody != nil && !c.expectBody.Match(body) {
+		failures = append(failures, "body does not match "+c.expectBody.String())
+	}
+	if c.rejectBody != nil && c.rejectBody.Match(body) {
+		failures = append(failures, "body matches rejected "+c.rejectBody.String())
+	}
+	if len(c.json) > 0 {
+		var doc interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return append(failures, "body is not valid JSON")
+		}
+		for _, j := range c.json {
+			v, ok := lookupJSONPath(doc, j.path)
+			if !ok {
+				failures = append(failures, "JSON path "+j.path+" not found")
+				continue
+			}
+			if j.hasValue && jsonValueString(v) != j.value {
+				failures = append(failures, fmt.Sprintf("JSON path %s does not equal %q", j.path, j.value))
+			}
+		}
+	}
+	return failures
+}
+
+// lookupJSONPath finds the value at a dot separated path in a decoded JSON document.
+// Array elements are selected by index, so "items.0.id" is the id of the first item.
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	v := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonValueString is how a JSON value is compared against an expected value:
+// strings without their quotes, everything else as compact JSON
+func jsonValueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,130 @@
+package pewpew
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNewResponseChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      TargetOptions
+		expectNil bool
+		expectErr bool
+	}{
+		{name: "no checks", opts: TargetOptions{}, expectNil: true},
+		{name: "status codes and classes", opts: TargetOptions{ExpectStatus: "200, 201,3XX"}},
+		{name: "invalid status", opts: TargetOptions{ExpectStatus: "ok"}, expectErr: true},
+		{name: "invalid status class", opts: TargetOptions{ExpectStatus: "9xx"}, expectErr: true},
+		{name: "headers", opts: TargetOptions{ExpectHeaders: "Content-Type:application/json, ETag"}},
+		{name: "header without name", opts: TargetOptions{ExpectHeaders: ":json"}, expectErr: true},
+		{name: "body regex", opts: TargetOptions{ExpectBodyRegex: "^ok$", RejectBodyRegex: "error"}},
+		{name: "invalid expected body regex", opts: TargetOptions{ExpectBodyRegex: "("}, expectErr: true},
+		{name: "invalid rejected body regex", opts: TargetOptions{RejectBodyRegex: "["}, expectErr: true},
+		{name: "json", opts: TargetOptions{ExpectJSON: []string{"data.id", "status=ok"}}},
+		{name: "json without path", opts: TargetOptions{ExpectJSON: []string{"=ok"}}, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			checks, err := newResponseChecks(tc.opts)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error: %t, wanted: %t", (err != nil), tc.expectErr)
+			}
+			if err == nil && (checks == nil) != tc.expectNil {
+				t.Errorf("got nil checks: %t, wanted: %t", checks == nil, tc.expectNil)
+			}
+		})
+	}
+}
+
+func TestResponseChecks(t *testing.T) {
+	jsonHeader := http.Header{"Content-Type": []string{"application/json; charset=utf-8"}}
+	jsonBody := []byte(`{"status": "ok", "count": 2, "items": [{"id": 7, "tags": ["a"]}], "deleted": null}`)
+	tests := []struct {
+		name       string
+		opts       TargetOptions
+		statusCode int
+		header     http.Header
+		body       []byte
+		want       []string
+	}{
+		{
+			name:       "no checks",
+			opts:       TargetOptions{},
+			statusCode: 500,
+		},
+		{
+			name:       "status in class",
+			opts:       TargetOptions{ExpectStatus: "200,3xx"},
+			statusCode: 302,
+		},
+		{
+			name:       "status not expected",
+			opts:       TargetOptions{ExpectStatus: "200,3xx"},
+			statusCode: 404,
+			want:       []string{"status not one of 200,3xx"},
+		},
+		{
+			name:       "headers",
+			opts:       TargetOptions{ExpectHeaders: "content-type:application/json, ETag, Content-Type:text/html"},
+			statusCode: 200,
+			header:     jsonHeader,
+			want:       []string{"missing header ETag", `header Content-Type does not contain "text/html"`},
+		},
+		{
+			name:       "body regex",
+			opts:       TargetOptions{ExpectBodyRegex: `"status": "ok"`, RejectBodyRegex: "error"},
+			statusCode: 200,
+			body:       jsonBody,
+		},
+		{
+			name:       "body regex failures",
+			opts:       TargetOptions{ExpectBodyRegex: "^ok$", RejectBodyRegex: "error"},
+			statusCode: 200,
+			body:       []byte("internal error"),
+			want:       []string{"body does not match ^ok$", "body matches rejected error"},
+		},
+		{
+			name:       "json paths",
+			opts:       TargetOptions{ExpectJSON: []string{"status=ok", "count=2", "items.0.id=7", "items.0.tags", "deleted=null"}},
+			statusCode: 200,
+			body:       jsonBody,
+		},
+		{
+			name:       "json path failures",
+			opts:       TargetOptions{ExpectJSON: []string{"status=fail", "items.1.id", "items.x", "count.value"}},
+			statusCode: 200,
+			body:       jsonBody,
+			want: []string{
+				`JSON path status does not equal "fail"`,
+				"JSON path items.1.id not found",
+				"JSON path items.x not found",
+				"JSON path count.value not found",
+			},
+		},
+		{
+			name:       "invalid json",
+			opts:       TargetOptions{ExpectStatus: "201", ExpectJSON: []string{"status"}},
+			statusCode: 200,
+			body:       []byte("<html>"),
+			want:       []string{"status not one of 201", "body is not valid JSON"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			checks, err := newResponseChecks(tc.opts)
+			if err != nil {
+				t.Fatalf("failed to create checks: %s", err)
+			}
+			got := checks.check(&http.Response{StatusCode: tc.statusCode, Header: tc.header}, tc.body)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package pewpew
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// significanceLevel is the p-value below which a difference in latency is significant
+const significanceLevel = 0.05
+
+// Comparison is how a candidate run's results differ from a baseline run's
+type Comparison struct {
+	Metrics     []ComparisonMetric
+	StatusCodes []StatusCodeComparison
+	Latency     LatencyComparison
+}
+
+// ComparisonMetric is one summary statistic of both runs
+type ComparisonMetric struct {
+	Name string
+	//Unit is "ms", "req/sec", "%", or "" for counts
+	Unit      string
+	Base      float64
+	Candidate float64
+	//HigherIsWorse is whether an increase is a regression, eg. for latency,
+	//rather than a decrease, eg. for throughput
+	HigherIsWorse bool
+	//Gated is whether the metric counts towards the regression limit
+	Gated bool
+}
+
+// Change is the difference from Base to Candidate. Metrics in percent are
+// compared in percentage points, all others as a percentage of Base,
+// which is infinite if Base is zero.
+func (m ComparisonMetric) Change() float64 {
+	if m.Unit == "%" {
+		return m.Candidate - m.Base
+	}
+	if m.Base == 0 {
+		if m.Candidate == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (m.Candidate - m.Base) / m.Base * 100
+}
+
+// Regression is how much worse the Candidate is than the Base, in the units of Change.
+// It is negative when the Candidate is better.
+func (m ComparisonMetric) Regression() float64 {
+	if m.HigherIsWorse {
+		return m.Change()
+	}
+	return -m.Change()
+}
+
+// StatusCodeComparison is how often a status code happened in both runs
+type StatusCodeComparison struct {
+	Code           int
+	BaseCount      int
+	CandidateCount int
+	//percentage of all responses with the code
+	BasePercent      float64
+	CandidatePercent float64
+}
+
+// LatencyComparison is the result of a Mann-Whitney U test of whether the
+// candidate's latency distribution differs from the baseline's
+type LatencyComparison struct {
+	//PValue is the two sided probability of a difference at least this large
+	//if both runs had the same latency distribution
+	PValue float64
+	//Significant is whether PValue is below the 0.05 significance level
+	Significant bool
+	//SlowerFraction is the probability that a random candidate request is
+	//slower than a random baseline request, 0.5 when neither is slower
+	SlowerFraction float64
+}
+
+// CompareRequestStats compares a candidate run's RequestStats to a baseline run's
+func CompareRequestStats(base, candidate []RequestStat) Comparison {
+	b := CreateRequestsStats(base)
+	c := CreateRequestsStats(candidate)
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+	comparison := Comparison{
+		Metrics: []ComparisonMetric{
+			{Name: "Requests", Base: float64(b.requestCount()), Candidate: float64(c.requestCount())},
+			{Name: "Mean RPS", Unit: "req/sec", Base: b.avgRPS * 1000000000, Candidate: c.avgRPS * 1000000000, Gated: true},
+			{Name: "Mean", Unit: "ms", Base: ms(b.avgDuration), Candidate: ms(c.avgDuration), HigherIsWorse: true, Gated: true},
+			{Name: "50%", Unit: "ms", Base: ms(b.p50Duration), Candidate: ms(c.p50Duration), HigherIsWorse: true, Gated: true},
+			{Name: "90%", Unit: "ms", Base: ms(b.p90Duration), Candidate: ms(c.p90Duration), HigherIsWorse: true, Gated: true},
+			{Name: "95%", Unit: "ms", Base: ms(b.p95Duration), Candidate: ms(c.p95Duration), HigherIsWorse: true, Gated: true},
+			{Name: "99%", Unit: "ms", Base: ms(b.p99Duration), Candidate: ms(c.p99Duration), HigherIsWorse: true, Gated: true},
+			{Name: "99.9%", Unit: "ms", Base: ms(b.p999Duration), Candidate: ms(c.p999Duration), HigherIsWorse: true},
+			{Name: "Max", Unit: "ms", Base: ms(b.maxDuration), Candidate: ms(c.maxDuration), HigherIsWorse: true},
+			{Name: "Error rate", Unit: "%", Base: b.errorRate() * 100, Candidate: c.errorRate() * 100, HigherIsWorse: true, Gated: true},
+		},
+	}
+
+	codes := make(map[int]bool)
+	for code := range b.statusCodes {
+		codes[code] = true
+	}
+	for code := range c.statusCodes {
+		codes[code] = true
+	}
+	baseResponses, candidateResponses := b.requestCount()-b.errorCount, c.requestCount()-c.errorCount
+	for code := range codes {
+		s := StatusCodeComparison{Code: code, BaseCount: b.statusCodes[code], CandidateCount: c.statusCodes[code]}
+		if baseResponses > 0 {
+			s.BasePercent = float64(s.BaseCount) / float64(baseResponses) * 100
+		}
+		if candidateResponses > 0 {
+			s.CandidatePercent = float64(s.CandidateCount) / float64(candidateResponses) * 100
+		}
+		comparison.StatusCodes = append(comparison.StatusCodes, s)
+	}
+	sort.Slice(comparison.StatusCodes, func(i, j int) bool {
+		return comparison.StatusCodes[i].Code < comparison.StatusCodes[j].Code
+	})
+
+	comparison.Latency = mannWhitneyU(responseDurations(base), responseDurations(candidate))
+	return comparison
+}
+
+// responseDurations are the durations of the requests that got a response
+func responseDurations(requestStats []RequestStat) []float64 {
+	durations := make([]float64, 0, len(requestStats))
+	for _, stat := range requestStats {
+		if stat.Dropped || stat.Error != nil {
+			continue
+		}
+		durations = append(durations, float64(stat.Duration))
+	}
+	return durations
+}
+
+// mannWhitneyU tests whether two samples come from the same distribution,
+// using the normal approximation with a correction for ties
+func mannWhitneyU(base, candidate []float64) LatencyComparison {
+	n1, n2 := float64(len(base)), float64(len(candidate))
+	if n1 == 0 || n2 == 0 {
+		return LatencyComparison{PValue: 1, SlowerFraction: 0.5}
+	}
+	type sample struct {
+		value  float64
+		isBase bool
+	}
+	samples := make([]sample, 0, len(base)+len(candidate))
+	for _, v := range base {
+		samples = append(samples, sample{value: v, isBase: true})
+	}
+	for _, v := range candidate {
+		samples = append(samples, sample{value: v})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].value < samples[j].value })
+
+	//rank from 1, giving tied values the mean of their ranks
+	var candidateRanks, tieCorrection float64
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if !samples[k].isBase {
+				candidateRanks += rank
+			}
+		}
+		ties := float64(j - i)
+		tieCorrection += ties*ties*ties - ties
+		i = j
+	}
+
+	//how many of the pairs of requests have the candidate slower, counting ties as half
+	u := candidateRanks - n2*(n2+1)/2
+	result := LatencyComparison{PValue: 1, SlowerFraction: u / (n1 * n2)}
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance <= 0 {
+		//every value is the same
+		return result
+	}
+	//continuity correction
+	diff := math.Abs(u-mean) - 0.5
+	if diff < 0 {
+		diff = 0
+	}
+	z := diff / math.Sqrt(variance)
+	result.PValue = math.Erfc(z / math.Sqrt2)
+	result.Significant = result.PValue < significanceLevel
+	return result
+}
+
+// Regressions are the gated metrics that got worse by more than maxRegression,
+// in the units of ComparisonMetric.Change
+func (c Comparison) Regressions(maxRegression float64) []ComparisonMetric {
+	var regressions []ComparisonMetric
+	for _, m := range c.Metrics {
+		if m.Gated && m.Regression() > maxRegression {
+			regressions = append(regressions, m)
+		}
+	}
+	return regressions
+}
+
+// CreateComparisonSummary creates a human friendly side by side table of a Comparison
+func CreateComparisonSummary(c Comparison) string {
+	summary := fmt.Sprintf("%-12s %14s %14s %12s\n", "", "Base", "Candidate", "Change")
+	for _, m := range c.Metrics {
+		summary += fmt.Sprintf("%-12s %14s %14s %12s\n", m.Name, formatMetric(m.Base, m.Unit), formatMetric(m.Candidate, m.Unit), formatChange(m))
+	}
+
+	summary += fmt.Sprintf("\n%-12s %14s %14s %12s\n", "Status code", "Base", "Candidate", "Change")
+	for _, s := range c.StatusCodes {
+		summary += fmt.Sprintf("%-12d %14s %14s %12s\n", s.Code,
+			fmt.Sprintf("%d (%.1f%%)", s.BaseCount, s.BasePercent),
+			fmt.Sprintf("%d (%.1f%%)", s.CandidateCount, s.CandidatePercent),
+			fmt.Sprintf("%+.2f pp", s.CandidatePercent-s.BasePercent))
+	}
+
+	summary += "\nLatency Distribution (Mann-Whitney U test)\n"
+	summary += fmt.Sprintf("p-value: %.4f\n", c.Latency.PValue)
+	switch {
+	case !c.Latency.Significant:
+		summary += "No significant difference in latency\n"
+	case c.Latency.SlowerFraction > 0.5:
+		summary += fmt.Sprintf("Candidate is significantly slower: %.1f%% of request pairs are slower in the candidate\n", c.Latency.SlowerFraction*100)
+	default:
+		summary += fmt.Sprintf("Candidate is significantly faster: %.1f%% of request pairs are faster in the candidate\n", (1-c.Latency.SlowerFraction)*100)
+	}
+	return summary
+}
+
+func formatMetric(v float64, unit string) string {
+	switch unit {
+	case "":
+		return fmt.Sprintf("%.0f", v)
+	case "%":
+		return fmt.Sprintf("%.2f%%", v)
+	default:
+		return fmt.Sprintf("%.2f %s", v, unit)
+	}
+}
+
+func formatChange(m ComparisonMetric) string {
+	change := m.Change()
+	switch {
+	case m.Unit == "%":
+		return fmt.Sprintf("%+.2f pp", change)
+	case math.IsInf(change, 0):
+		//nothing to compare to
+		return "n/a"
+	default:
+		return fmt.Sprintf("%+.2f%%", change)
+	}
+}
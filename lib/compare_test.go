@@ -0,0 +1,139 @@
+package pewpew
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name            string
+		base            []float64
+		candidate       []float64
+		wantPValue      float64
+		wantSignificant bool
+		wantSlower      float64
+	}{
+		{name: "empty", base: []float64{}, candidate: []float64{1, 2}, wantPValue: 1, wantSlower: 0.5},
+		{name: "all the same", base: []float64{3, 3, 3}, candidate: []float64{3, 3}, wantPValue: 1, wantSlower: 0.5},
+		{name: "candidate slower", base: []float64{1, 2, 3, 4, 5}, candidate: []float64{6, 7, 8, 9, 10}, wantPValue: 0.0122, wantSignificant: true, wantSlower: 1},
+		{name: "candidate faster", base: []float64{6, 7, 8, 9, 10}, candidate: []float64{1, 2, 3, 4, 5}, wantPValue: 0.0122, wantSignificant: true, wantSlower: 0},
+		{name: "overlapping with ties", base: []float64{1, 2, 3, 4}, candidate: []float64{2, 3, 4, 5}, wantPValue: 0.3778, wantSlower: 0.71875},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := mannWhitneyU(tc.base, tc.candidate)
+			if math.Abs(got.PValue-tc.wantPValue) > 0.0001 {
+				t.Errorf("got p-value %.4f, wanted %.4f", got.PValue, tc.wantPValue)
+			}
+			if got.Significant != tc.wantSignificant {
+				t.Errorf("got significant %t, wanted %t", got.Significant, tc.wantSignificant)
+			}
+			if got.SlowerFraction != tc.wantSlower {
+				t.Errorf("got slower fraction %f, wanted %f", got.SlowerFraction, tc.wantSlower)
+			}
+		})
+	}
+}
+
+func TestCompareRequestStats(t *testing.T) {
+	start := time.Unix(1000, 0)
+	makeStats := func(duration time.Duration, codes ...int) []RequestStat {
+		stats := []RequestStat{}
+		for i, code := range codes {
+			stat := RequestStat{
+				StartTime:  start.Add(time.Duration(i) * time.Second),
+				EndTime:    start.Add(time.Duration(i)*time.Second + duration),
+				Duration:   duration,
+				StatusCode: code,
+			}
+			if code == 0 {
+				stat.Error = errors.New("test error")
+			}
+			stats = append(stats, stat)
+		}
+		return stats
+	}
+	base := makeStats(100*time.Millisecond, 200, 200, 200, 404)
+	candidate := makeStats(200*time.Millisecond, 200, 200, 503, 0)
+
+	c := CompareRequestStats(base, candidate)
+	metrics := make(map[string]ComparisonMetric)
+	for _, m := range c.Metrics {
+		metrics[m.Name] = m
+	}
+	if m := metrics["Mean"]; m.Base != 100 || m.Candidate != 200 || m.Change() != 100 || m.Regression() != 100 {
+		t.Errorf("got mean %+v with change %f, wanted 100ms to 200ms", m, m.Change())
+	}
+	if m := metrics["Error rate"]; m.Base != 0 || m.Candidate != 50 || m.Change() != 50 {
+		t.Errorf("got error rate %+v, wanted 0%% to 50%%", m)
+	}
+	wantCodes := []StatusCodeComparison{
+		{Code: 200, BaseCount: 3, CandidateCount: 2, BasePercent: 75, CandidatePercent: float64(2) / float64(3) * 100},
+		{Code: 404, BaseCount: 1, BasePercent: 25},
+		{Code: 503, CandidateCount: 1, CandidatePercent: float64(1) / float64(3) * 100},
+	}
+	if len(c.StatusCodes) != len(wantCodes) {
+		t.Fatalf("got status codes %+v, wanted %+v", c.StatusCodes, wantCodes)
+	}
+	for i := range wantCodes {
+		if c.StatusCodes[i] != wantCodes[i] {
+			t.Errorf("got status code %+v, wanted %+v", c.StatusCodes[i], wantCodes[i])
+		}
+	}
+	if c.Latency.SlowerFraction != 1 {
+		t.Errorf("got slower fraction %f, wanted 1", c.Latency.SlowerFraction)
+	}
+
+	if regressions := c.Regressions(1000); len(regressions) != 0 {
+		t.Errorf("got regressions %+v over 1000%%, wanted none", regressions)
+	}
+	regressions := c.Regressions(60)
+	names := []string{}
+	for _, m := range regressions {
+		names = append(names, m.Name)
+	}
+	//99.9% and Max aren't gated, since single slow requests make them noisy
+	if strings.Join(names, ",") != "Mean,50%,90%,95%,99%" {
+		t.Errorf("got regressions %v over 60%%", names)
+	}
+
+	summary := CreateComparisonSummary(c)
+	for _, want := range []string{"Candidate", "+100.00%", "+50.00 pp", "503", "p-value"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestComparisonMetricChange(t *testing.T) {
+	tests := []struct {
+		name           string
+		metric         ComparisonMetric
+		wantChange     float64
+		wantRegression float64
+	}{
+		{name: "latency up", metric: ComparisonMetric{Unit: "ms", Base: 10, Candidate: 15, HigherIsWorse: true}, wantChange: 50, wantRegression: 50},
+		{name: "throughput up", metric: ComparisonMetric{Unit: "req/sec", Base: 100, Candidate: 110}, wantChange: 10, wantRegression: -10},
+		{name: "percentage points", metric: ComparisonMetric{Unit: "%", Base: 1, Candidate: 3, HigherIsWorse: true}, wantChange: 2, wantRegression: 2},
+		{name: "both zero", metric: ComparisonMetric{Unit: "ms", HigherIsWorse: true}, wantChange: 0, wantRegression: 0},
+		{name: "from zero", metric: ComparisonMetric{Unit: "ms", Candidate: 1, HigherIsWorse: true}, wantChange: math.Inf(1), wantRegression: math.Inf(1)},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.metric.Change(); got != tc.wantChange {
+				t.Errorf("got change %f, wanted %f", got, tc.wantChange)
+			}
+			if got := tc.metric.Regression(); got != tc.wantRegression {
+				t.Errorf("got regression %f, wanted %f", got, tc.wantRegression)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package pewpew
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// curlIgnoredFlags are curl options that don't change the request sent,
+// mapped to whether they take an argument
+var curlIgnoredFlags = map[string]bool{
+	"-s": false, "--silent": false,
+	"-S": false, "--show-error": false,
+	"-v": false, "--verbose": false,
+	"-i": false, "--include": false,
+	"-f": false, "--fail": false,
+	"-#": false, "--progress-bar": false, "--no-progress-meter": false,
+	"-o": true, "--output": true,
+	"-w": true, "--write-out": true,
+	"--connect-timeout": true,
+}
+
+// ParseCurl creates a Target that sends the same request as a curl command
+// line, such as one copied from a browser's developer tools. The command is
+// split into arguments like a POSIX shell would, and can start with "curl".
+func ParseCurl(command string) (Target, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return Target{}, err
+	}
+	return ParseCurlArgs(args)
+}
+
+// ParseCurlArgs creates a Target that sends the same request as curl would
+// with args. The Options follow curl's defaults where they differ from
+// pewpew's: no compression, no redirects, and certificates are checked.
+func ParseCurlArgs(args []string) (Target, error) {
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+	t := Target{Options: TargetOptions{
+		Method:     DefaultMethod,
+		UserAgent:  DefaultUserAgent,
+		EnforceSSL: true,
+		KeepAlive:  true,
+		RawHeaders: make(map[string]string),
+	}}
+	var method string
+	var data []string
+	var dataFile string
+	var head, get bool
+	args = expandCurlArgs(args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if t.URL != "" {
+				return Target{}, fmt.Errorf("more than one URL: %s and %s", t.URL, arg)
+			}
+			t.URL = arg
+			continue
+		}
+
+		flag, value := arg, ""
+		if curlTakesValue(flag) {
+			if i+1 >= len(args) {
+				return Target{}, fmt.Errorf("curl option %s requires a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "--url":
+			if t.URL != "" {
+				return Target{}, fmt.Errorf("more than one URL: %s and %s", t.URL, value)
+			}
+			t.URL = value
+		case "-X", "--request":
+			method = value
+		case "-I", "--head":
+			head = true
+		case "-G", "--get":
+			get = true
+		case "-H", "--header":
+			parts := strings.SplitN(value, ":", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return Target{}, fmt.Errorf("invalid header %q", value)
+			}
+			name := strings.TrimSpace(parts[0])
+			t.Options.RawHeaders[canonicalHeaderKey(t.Options.RawHeaders, name)] = strings.TrimSpace(parts[1])
+		case "-A", "--user-agent":
+			t.Options.UserAgent = value
+		case "-e", "--referer":
+			t.Options.RawHeaders["Referer"] = value
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				if dataFile != "" || len(data) > 0 {
+					return Target{}, errors.New("a body file can't be combined with other data")
+				}
+				dataFile = value[1:]
+				continue
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			data = append(data, curlURLEncode(value))
+		case "-u", "--user":
+			t.Options.BasicAuth = value
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				return Target{}, fmt.Errorf("cookie files are not supported: %s", value)
+			}
+			if t.Options.Cookies != "" {
+				t.Options.Cookies += "; "
+			}
+			t.Options.Cookies += value
+		case "-k", "--insecure":
+			t.Options.EnforceSSL = false
+		case "--compressed":
+			t.Options.Compress = true
+		case "-L", "--location":
+			t.Options.FollowRedirects = true
+		case "-m", "--max-time":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				return Target{}, fmt.Errorf("invalid max time %q", value)
+			}
+			t.Options.Timeout = time.Duration(seconds * float64(time.Second)).String()
+		case "--http1.1", "--http1.0":
+			t.Options.NoHTTP2 = true
+		case "--http2":
+			t.Options.NoHTTP2 = false
+		default:
+			if _, ok := curlIgnoredFlags[flag]; !ok {
+				return Target{}, fmt.Errorf("unsupported curl option %s", flag)
+			}
+		}
+	}
+	if t.URL == "" {
+		return Target{}, errors.New("no URL in curl command")
+	}
+
+	body := strings.Join(data, "&")
+	hasData := len(data) > 0 || dataFile != ""
+	switch {
+	case get:
+		//-G sends the data as the query string instead
+		if dataFile != "" {
+			return Target{}, errors.New("a body file can't be sent with --get")
+		}
+		if body != "" {
+			if strings.Contains(t.URL, "?") {
+				t.URL += "&" + body
+			} else {
+				t.URL += "?" + body
+			}
+		}
+		t.Options.Method = "GET"
+	case head:
+		t.Options.Method = "HEAD"
+	case hasData:
+		t.Options.Method = "POST"
+		t.Options.Body = body
+		t.Options.BodyFilename = dataFile
+		if _, ok := t.Options.RawHeaders[canonicalHeaderKey(t.Options.RawHeaders, "Content-Type")]; !ok {
+			t.Options.RawHeaders["Content-Type"] = "application/x-www-form-urlencoded"
+		}
+	}
+	if method != "" {
+		t.Options.Method = method
+	}
+	if len(t.Options.RawHeaders) == 0 {
+		t.Options.RawHeaders = nil
+	}
+	return t, nil
+}
+
+// expandCurlArgs splits combined short options, like -sSL, and short options
+// with their value attached, like -XPOST, into separate arguments
+func expandCurlArgs(args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") || len(arg) <= 2 {
+			expanded = append(expanded, arg)
+			//the value of an option is kept as is, even if it looks like an option
+			if curlTakesValue(arg) && i+1 < len(args) {
+				i++
+				expanded = append(expanded, args[i])
+			}
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			flag := "-" + arg[j:j+1]
+			expanded = append(expanded, flag)
+			if curlTakesValue(flag) {
+				if j+1 < len(arg) {
+					expanded = append(expanded, arg[j+1:])
+				} else if i+1 < len(args) {
+					i++
+					expanded = append(expanded, args[i])
+				}
+				break
+			}
+		}
+	}
+	return expanded
+}
+
+// curlTakesValue is whether a curl option is followed by a value
+func curlTakesValue(flag string) bool {
+	switch flag {
+	case "--url", "-X", "--request", "-H", "--header", "-A", "--user-agent", "-e", "--referer",
+		"-d", "--data", "--data-ascii", "--data-binary", "--data-raw", "--data-urlencode",
+		"-u", "--user", "-b", "--cookie", "-m", "--max-time":
+		return true
+	}
+	return curlIgnoredFlags[flag]
+}
+
+// curlURLEncode encodes the value of --data-urlencode, which is either
+// "content", "=content", or "name=content", where only content is encoded
+func curlURLEncode(value string) string {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return url.QueryEscape(value)
+	}
+	if i == 0 {
+		return url.QueryEscape(value[1:])
+	}
+	return value[:i] + "=" + url.QueryEscape(value[i+1:])
+}
+
+// splitShellWords splits a command line into arguments the way a POSIX shell
+// would, handling single quotes, double quotes, $'...' quotes, backslash
+// escapes, and line continuations
+func splitShellWords(command string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			i++
+			if i >= len(command) {
+				return nil, errors.New("unfinished escape at end of command")
+			}
+			//a backslash before a newline continues the line
+			if command[i] == '\n' {
+				continue
+			}
+			if command[i] == '\r' && i+1 < len(command) && command[i+1] == '\n' {
+				i++
+				continue
+			}
+			word.WriteByte(command[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				//only these characters can be escaped in double quotes
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`\n", command[i+1]) >= 0 {
+					i++
+					if command[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(command[i])
+			}
+			if i >= len(command) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == '$' && i+1 < len(command) && command[i+1] == '\'':
+			n, err := readANSIQuote(command[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// readANSIQuote reads the contents of a $'...' quote, starting after the
+// opening quote, into word. It returns how many bytes it read, including the
+// closing quote.
+func readANSIQuote(s string, word *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', 'a': '\a', 'b': '\b',
+		'f': '\f', 'v': '\v', 'e': 0x1b, '\\': '\\', '\'': '\'', '"': '"', '?': '?'}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			return i + 1, nil
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			if b, ok := escapes[s[i]]; ok {
+				word.WriteByte(b)
+				continue
+			}
+			if s[i] == 'x' && i+2 < len(s) {
+				if b, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					word.WriteByte(byte(b))
+					i += 2
+					continue
+				}
+			}
+			word.WriteByte('\\')
+			word.WriteByte(s[i])
+		default:
+			word.WriteByte(s[i])
+		}
+	}
+	return 0, errors.New("unterminated $' quote")
+}
@@ -0,0 +1,164 @@
+package pewpew
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		want      []string
+		expectErr bool
+	}{
+		{name: "empty", command: "  ", want: nil},
+		{name: "plain", command: "curl  -k\thttp://localhost", want: []string{"curl", "-k", "http://localhost"}},
+		{name: "single quotes", command: `curl -H 'Accept: a, "b"'`, want: []string{"curl", "-H", `Accept: a, "b"`}},
+		{name: "double quotes", command: `curl -d "{\"a\": \"\$1\\\\\"}"`, want: []string{"curl", "-d", `{"a": "$1\\"}`}},
+		{name: "backslash escapes", command: `curl a\ b\'c`, want: []string{"curl", "a b'c"}},
+		{name: "line continuations", command: "curl \\\n  -k \\\r\n  localhost", want: []string{"curl", "-k", "localhost"}},
+		{name: "ansi c quotes", command: `curl --data-raw $'a\nb\'c\x41'`, want: []string{"curl", "--data-raw", "a\nb'cA"}},
+		{name: "adjacent quotes", command: `curl 'a'"b"c`, want: []string{"curl", "abc"}},
+		{name: "empty quotes", command: `curl ''`, want: []string{"curl", ""}},
+		{name: "unterminated single quote", command: `curl 'a`, expectErr: true},
+		{name: "unterminated double quote", command: `curl "a`, expectErr: true},
+		{name: "unterminated ansi c quote", command: `curl $'a`, expectErr: true},
+		{name: "trailing backslash", command: `curl \`, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			words, err := splitShellWords(tc.command)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if !reflect.DeepEqual(words, tc.want) {
+				t.Errorf("got %q, wanted %q", words, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	//curl's defaults, which some cases change
+	defaults := TargetOptions{Method: "GET", UserAgent: "pewpew", EnforceSSL: true, KeepAlive: true}
+	with := func(f func(o *TargetOptions)) TargetOptions {
+		o := defaults
+		f(&o)
+		return o
+	}
+	tests := []struct {
+		name      string
+		command   string
+		wantURL   string
+		want      TargetOptions
+		expectErr bool
+	}{
+		{name: "url only", command: "curl http://localhost/a", wantURL: "http://localhost/a", want: defaults},
+		{name: "without curl", command: "--url http://localhost/a", wantURL: "http://localhost/a", want: defaults},
+		{
+			name:    "method and headers",
+			command: `curl -X PUT 'http://localhost/a' -H 'Accept: text/html, application/json' -H "X-Test:1" -A agent`,
+			wantURL: "http://localhost/a",
+			want: with(func(o *TargetOptions) {
+				o.Method = "PUT"
+				o.UserAgent = "agent"
+				o.RawHeaders = map[string]string{"Accept": "text/html, application/json", "X-Test": "1"}
+			}),
+		},
+		{
+			name:    "combined short options",
+			command: `curl -sSLk -XDELETE -H'X-Test: 1' localhost`,
+			wantURL: "localhost",
+			want: with(func(o *TargetOptions) {
+				o.Method = "DELETE"
+				o.FollowRedirects = true
+				o.EnforceSSL = false
+				o.RawHeaders = map[string]string{"X-Test": "1"}
+			}),
+		},
+		{
+			name:    "data",
+			command: `curl localhost -d a=1 --data-raw '@b=2' --data-urlencode 'c=x y'`,
+			wantURL: "localhost",
+			want: with(func(o *TargetOptions) {
+				o.Method = "POST"
+				o.Body = "a=1&@b=2&c=x+y"
+				o.RawHeaders = map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+			}),
+		},
+		{
+			name:    "json data",
+			command: `curl localhost -H 'content-type: application/json' --data-binary '{"a": 1}' -X PATCH`,
+			wantURL: "localhost",
+			want: with(func(o *TargetOptions) {
+				o.Method = "PATCH"
+				o.Body = `{"a": 1}`
+				o.RawHeaders = map[string]string{"content-type": "application/json"}
+			}),
+		},
+		{
+			name:    "data file",
+			command: `curl localhost --data-binary @body.json`,
+			wantURL: "localhost",
+			want: with(func(o *TargetOptions) {
+				o.Method = "POST"
+				o.BodyFilename = "body.json"
+				o.RawHeaders = map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+			}),
+		},
+		{
+			name:    "get data",
+			command: `curl -G 'localhost/a?x=1' -d y=2 -d z=3`,
+			wantURL: "localhost/a?x=1&y=2&z=3",
+			want:    defaults,
+		},
+		{
+			name:    "head",
+			command: `curl -I localhost`,
+			wantURL: "localhost",
+			want:    with(func(o *TargetOptions) { o.Method = "HEAD" }),
+		},
+		{
+			name:    "auth, cookies, and transport",
+			command: `curl -u user:pass -b 'a=1' --cookie 'b=2' --compressed --max-time 2.5 --http1.1 -o /dev/null -w '%{http_code}' localhost`,
+			wantURL: "localhost",
+			want: with(func(o *TargetOptions) {
+				o.BasicAuth = "user:pass"
+				o.Cookies = "a=1; b=2"
+				o.Compress = true
+				o.Timeout = "2.5s"
+				o.NoHTTP2 = true
+			}),
+		},
+		{name: "no url", command: "curl -k", expectErr: true},
+		{name: "two urls", command: "curl localhost/a localhost/b", expectErr: true},
+		{name: "missing value", command: "curl localhost -H", expectErr: true},
+		{name: "invalid header", command: "curl localhost -H nocolon", expectErr: true},
+		{name: "cookie file", command: "curl localhost -b cookies.txt", expectErr: true},
+		{name: "invalid max time", command: "curl localhost -m soon", expectErr: true},
+		{name: "unsupported option", command: "curl localhost -F file=@a.txt", expectErr: true},
+		{name: "unterminated quote", command: "curl 'localhost", expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			target, err := ParseCurl(tc.command)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if target.URL != tc.wantURL {
+				t.Errorf("got URL %q, wanted %q", target.URL, tc.wantURL)
+			}
+			if !reflect.DeepEqual(target.Options, tc.want) {
+				t.Errorf("got options %+v, wanted %+v", target.Options, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package pewpew
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Extraction sources, where an extractor finds its value in a response
+const (
+	//ExtractJSON is a dot separated JSON path in the body, eg. "data.items.0.id"
+	ExtractJSON = "json"
+	//ExtractRegex is a regular expression matched against the body. The value is
+	//the first capture group, or the whole match if there are none.
+	ExtractRegex = "regex"
+	//ExtractHeader is the name of a response header
+	ExtractHeader = "header"
+	//ExtractCookie is the name of a cookie set by the response
+	ExtractCookie = "cookie"
+)
+
+// extractor sets a scenario variable from a response,
+// parsed from an extraction of the form NAME=SOURCE:EXPRESSION
+type extractor struct {
+	name   string
+	source string
+	expr   string
+	re     *regexp.Regexp
+}
+
+func newExtractor(spec string) (extractor, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return extractor{}, fmt.Errorf("invalid extraction %q, must be NAME=SOURCE:EXPRESSION", spec)
+	}
+	e := extractor{name: strings.TrimSpace(parts[0])}
+	parts = strings.SplitN(parts[1], ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return extractor{}, fmt.Errorf("invalid extraction %q, must be NAME=SOURCE:EXPRESSION", spec)
+	}
+	e.source = strings.ToLower(strings.TrimSpace(parts[0]))
+	e.expr = parts[1]
+	switch e.source {
+	case ExtractJSON, ExtractHeader, ExtractCookie:
+		e.expr = strings.TrimSpace(e.expr)
+	case ExtractRegex:
+		var err error
+		e.re, err = regexp.Compile(e.expr)
+		if err != nil {
+			return extractor{}, fmt.Errorf("failed to compile extraction regex for %s: %w", e.name, err)
+		}
+	default:
+		return extractor{}, fmt.Errorf("unknown extraction source %q for %s, must be one of %s, %s, %s, %s",
+			e.source, e.name, ExtractJSON, ExtractRegex, ExtractHeader, ExtractCookie)
+	}
+	return e, nil
+}
+
+// extractVariables sets a variable in vars for each of the extractors,
+// returning an error for the first one that isn't found in the response
+func extractVariables(extractors []extractor, response *http.Response, body []byte, vars map[string]string) error {
+	//only decoded if there's a JSON extraction, and only once
+	var doc interface{}
+	decoded := false
+	for _, e := range extractors {
+		switch e.source {
+		case ExtractJSON:
+			if !decoded {
+				decoder := json.NewDecoder(bytes.NewReader(body))
+				decoder.UseNumber()
+				if err := decoder.Decode(&doc); err != nil {
+					return fmt.Errorf("failed to extract %s: body is not valid JSON", e.name)
+				}
+				decoded = true
+			}
+			v, ok := lookupJSONPath(doc, e.expr)
+			if !ok {
+				return fmt.Errorf("failed to extract %s: JSON path %s not found", e.name, e.expr)
+			}
+			vars[e.name] = jsonValueString(v)
+		case ExtractRegex:
+			match := e.re.FindSubmatch(body)
+			if match == nil {
+				return fmt.Errorf("failed to extract %s: body does not match %s", e.name, e.expr)
+			}
+			if len(match) > 1 {
+				vars[e.name] = string(match[1])
+			} else {
+				vars[e.name] = string(match[0])
+			}
+		case ExtractHeader:
+			values, ok := response.Header[http.CanonicalHeaderKey(e.expr)]
+			if !ok {
+				return fmt.Errorf("failed to extract %s: missing header %s", e.name, e.expr)
+			}
+			vars[e.name] = strings.Join(values, ", ")
+		case ExtractCookie:
+			found := false
+			for _, cookie := range response.Cookies() {
+				if cookie.Name == e.expr {
+					vars[e.name] = cookie.Value
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("failed to extract %s: missing cookie %s", e.name, e.expr)
+			}
+		}
+	}
+	return nil
+}
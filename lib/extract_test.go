@@ -0,0 +1,93 @@
+package pewpew
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewExtractor(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		want      extractor
+		expectErr bool
+	}{
+		{name: "json", spec: "token = json: data.token", want: extractor{name: "token", source: ExtractJSON, expr: "data.token"}},
+		{name: "header", spec: "loc=HEADER:Location", want: extractor{name: "loc", source: ExtractHeader, expr: "Location"}},
+		{name: "cookie", spec: "session=cookie:SESSIONID", want: extractor{name: "session", source: ExtractCookie, expr: "SESSIONID"}},
+		{name: "regex keeps spaces and colons", spec: `id=regex:"id": (\d+)`, want: extractor{name: "id", source: ExtractRegex, expr: `"id": (\d+)`}},
+		{name: "no name", spec: "=json:id", expectErr: true},
+		{name: "no source", spec: "id=data.id", expectErr: true},
+		{name: "no expression", spec: "id=json:", expectErr: true},
+		{name: "unknown source", spec: "id=xpath://id", expectErr: true},
+		{name: "invalid regex", spec: "id=regex:(", expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, err := newExtractor(tc.spec)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			e.re = nil
+			if e != tc.want {
+				t.Errorf("got %+v, wanted %+v", e, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractVariables(t *testing.T) {
+	response := &http.Response{Header: http.Header{
+		"Location":   []string{"/items/7"},
+		"Set-Cookie": []string{"SESSIONID=abc; Path=/", "theme=dark"},
+	}}
+	body := []byte(`{"data": {"token": "t0k", "items": [{"id": 7}]}}`)
+	tests := []struct {
+		name      string
+		specs     []string
+		body      []byte
+		want      map[string]string
+		expectErr bool
+	}{
+		{
+			name:  "every source",
+			specs: []string{"token=json:data.token", "id=json:data.items.0.id", "loc=header:location", "session=cookie:SESSIONID", "whole=regex:t0k", "group=regex:\"id\": (\\d+)"},
+			body:  body,
+			want:  map[string]string{"token": "t0k", "id": "7", "loc": "/items/7", "session": "abc", "whole": "t0k", "group": "7"},
+		},
+		{name: "missing json path", specs: []string{"id=json:data.id"}, body: body, expectErr: true},
+		{name: "body not json", specs: []string{"id=json:id"}, body: []byte("<html>"), expectErr: true},
+		{name: "no regex match", specs: []string{"id=regex:nope"}, body: body, expectErr: true},
+		{name: "missing header", specs: []string{"etag=header:ETag"}, body: body, expectErr: true},
+		{name: "missing cookie", specs: []string{"c=cookie:other"}, body: body, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var extractors []extractor
+			for _, spec := range tc.specs {
+				e, err := newExtractor(spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				extractors = append(extractors, e)
+			}
+			vars := make(map[string]string)
+			err := extractVariables(extractors, response, tc.body, vars)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			for name, want := range tc.want {
+				if vars[name] != want {
+					t.Errorf("got %s %q, wanted %q", name, vars[name], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package pewpew
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Data orders control which row of a Target's DataFile is used for the next request
+const (
+	//DataOrderSequential uses the rows in order, starting over after the last one
+	DataOrderSequential = "sequential"
+	//DataOrderRandom picks a row at random for each request
+	DataOrderRandom = "random"
+	//DataOrderUnique uses each row for one request, in order, then stops
+	DataOrderUnique = "unique"
+)
+
+// dataFeeder picks the row of a data file to use for each request
+type dataFeeder struct {
+	rows  []map[string]string
+	order string
+	//index of the next row in sequential and unique order
+	pos int
+	rng *rand.Rand
+}
+
+func newDataFeeder(filename, order string) (*dataFeeder, error) {
+	rows, err := readDataFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f := &dataFeeder{rows: rows, order: order}
+	if f.order == "" {
+		f.order = DataOrderSequential
+	}
+	if f.order == DataOrderRandom {
+		f.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return f, nil
+}
+
+// next returns the row for the next request, or false once there are none left
+func (f *dataFeeder) next() (map[string]string, bool) {
+	var i int
+	switch f.order {
+	case DataOrderRandom:
+		i = f.rng.Intn(len(f.rows))
+	case DataOrderUnique:
+		if f.pos >= len(f.rows) {
+			return nil, false
+		}
+		i = f.pos
+		f.pos++
+	default:
+		i = f.pos
+		f.pos = (f.pos + 1) % len(f.rows)
+	}
+	return f.rows[i], true
+}
+
+// readDataFile reads the rows of a CSV file, whose first line names the
+// columns, or a JSON file with an array of objects. Every row has every
+// column, with missing JSON fields empty.
+func readDataFile(filename string) ([]map[string]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		rows, err = parseCSVRows(data)
+	case ".json":
+		rows, err = parseJSONRows(data)
+	default:
+		return nil, fmt.Errorf("unknown data file type %s, must be .csv or .json", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data file %s: %w", filename, err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows in data file " + filename)
+	}
+	return rows, nil
+}
+
+func parseCSVRows(data []byte) ([]map[string]string, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+		if header[i] == "" {
+			return nil, fmt.Errorf("column %d has no name", i+1)
+		}
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseJSONRows(data []byte) ([]map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	//keep numbers as written, instead of as floats
+	decoder.UseNumber()
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool)
+	for _, object := range objects {
+		for name := range object {
+			columns[name] = true
+		}
+	}
+	rows := make([]map[string]string, len(objects))
+	for i, object := range objects {
+		rows[i] = make(map[string]string, len(columns))
+		for name := range columns {
+			switch v := object[name].(type) {
+			case nil:
+				rows[i][name] = ""
+			case string:
+				rows[i][name] = v
+			case json.Number:
+				rows[i][name] = v.String()
+			default:
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				rows[i][name] = string(b)
+			}
+		}
+	}
+	return rows, nil
+}
+
+func validateDataOrder(order string) error {
+	switch order {
+	case "", DataOrderSequential, DataOrderRandom, DataOrderUnique:
+		return nil
+	default:
+		return fmt.Errorf("unknown data order %q, must be one of %s, %s, %s", order, DataOrderSequential, DataOrderRandom, DataOrderUnique)
+	}
+}
@@ -0,0 +1,185 @@
+package pewpew
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDataFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		contents  string
+		want      []map[string]string
+		expectErr bool
+	}{
+		{
+			name:     "csv",
+			filename: "users.csv",
+			contents: "id, name\n1,\"Doe, Jane\"\n2,bob\n",
+			want:     []map[string]string{{"id": "1", "name": "Doe, Jane"}, {"id": "2", "name": "bob"}},
+		},
+		{
+			name:     "json",
+			filename: "users.JSON",
+			contents: `[{"id": 12345678901234567890, "name": "jane", "admin": true}, {"id": 2, "tags": ["a"], "name": null}]`,
+			want: []map[string]string{
+				{"id": "12345678901234567890", "name": "jane", "admin": "true", "tags": ""},
+				{"id": "2", "name": "", "admin": "", "tags": `["a"]`},
+			},
+		},
+		{name: "csv header only", filename: "users.csv", contents: "id,name\n", expectErr: true},
+		{name: "csv unnamed column", filename: "users.csv", contents: "id,\n1,2\n", expectErr: true},
+		{name: "csv uneven rows", filename: "users.csv", contents: "id,name\n1\n", expectErr: true},
+		{name: "json not an array", filename: "users.json", contents: `{"id": 1}`, expectErr: true},
+		{name: "json empty", filename: "users.json", contents: `[]`, expectErr: true},
+		{name: "unknown type", filename: "users.txt", contents: "id\n1\n", expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rows, err := readDataFile(writeTempFile(t, tc.filename, tc.contents))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if !tc.expectErr && !reflect.DeepEqual(rows, tc.want) {
+				t.Errorf("got rows %v, wanted %v", rows, tc.want)
+			}
+		})
+	}
+	if _, err := readDataFile("/nonexistent/users.csv"); err == nil {
+		t.Error("got no error for missing file")
+	}
+}
+
+func TestDataFeeder(t *testing.T) {
+	filename := writeTempFile(t, "ids.csv", "id\na\nb\nc\n")
+	tests := []struct {
+		order string
+		want  string //ids of the first rows, with "-" when there are none left
+	}{
+		{order: "", want: "abcab"},
+		{order: DataOrderSequential, want: "abcab"},
+		{order: DataOrderUnique, want: "abc--"},
+	}
+	for _, tc := range tests {
+		feeder, err := newDataFeeder(filename, tc.order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for i := 0; i < len(tc.want); i++ {
+			row, ok := feeder.next()
+			if ok {
+				got += row["id"]
+			} else {
+				got += "-"
+			}
+		}
+		if got != tc.want {
+			t.Errorf("got %s in %q order, wanted %s", got, tc.order, tc.want)
+		}
+	}
+
+	feeder, err := newDataFeeder(filename, DataOrderRandom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		row, ok := feeder.next()
+		if !ok {
+			t.Fatal("random order ran out of rows")
+		}
+		seen[row["id"]] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("got rows %v in random order, wanted all three", seen)
+	}
+}
+
+func TestDataFileRequestSource(t *testing.T) {
+	target := Target{
+		URL:       "http://localhost/users/{{.id}}?n={{seq}}",
+		DataFile:  writeTempFile(t, "users.json", `[{"id": 1, "user": "ann", "pass": "a"}, {"id": 2, "user": "bob", "pass": "b"}]`),
+		DataOrder: DataOrderUnique,
+		Options: TargetOptions{
+			Method:     "POST",
+			Body:       `{"user": "{{.user}}"}`,
+			BasicAuth:  "{{.user}}:{{.pass}}",
+			RawHeaders: map[string]string{"X-User": "{{.user}}"},
+		},
+	}
+	source, err := newRequestSource(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the trial request doesn't use up a row
+	for i, want := range []struct{ path, query, user, pass string }{{"/users/1", "n=1", "ann", "a"}, {"/users/2", "n=2", "bob", "b"}} {
+		req, ok, err := source.next()
+		if err != nil || !ok {
+			t.Fatalf("got error %v, ok %t", err, ok)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		user, pass, _ := req.BasicAuth()
+		if req.URL.Path != want.path || req.URL.RawQuery != want.query || user != want.user || pass != want.pass ||
+			string(body) != `{"user": "`+want.user+`"}` || req.Header.Get("X-User") != want.user {
+			t.Errorf("got request %d to %s with auth %s:%s, body %s, headers %v", i, req.URL, user, pass, body, req.Header)
+		}
+	}
+	if _, ok, _ := source.next(); ok {
+		t.Error("got a request after every row was used")
+	}
+
+	//a column that isn't in the data file
+	target.URL = "http://localhost/users/{{.userid}}"
+	if _, err := newRequestSource(target); err == nil {
+		t.Error("got no error for an unknown column")
+	}
+	target.DataFile = "/nonexistent/users.json"
+	if _, err := newRequestSource(target); err == nil {
+		t.Error("got no error for a missing data file")
+	}
+}
+
+func TestDataFileInvalidRow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	//the second row makes an invalid URL, so that request fails without being sent
+	s := StressConfig{
+		Count:       3,
+		Concurrency: 1,
+		Quiet:       true,
+		Targets: []Target{{
+			URL:      server.URL + "/{{.path}}",
+			DataFile: writeTempFile(t, "paths.csv", "path\na\n%zz\nb\n"),
+			Options:  TargetOptions{Method: "GET"},
+		}},
+	}
+	stats, err := RunStress(s, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats[0]) != 3 {
+		t.Fatalf("got %d requests, wanted 3", len(stats[0]))
+	}
+	failed := 0
+	for _, stat := range stats[0] {
+		if stat.Error == nil {
+			continue
+		}
+		failed++
+		if !strings.HasPrefix(stat.Error.Error(), "failed to create request: ") {
+			t.Errorf("got error %v", stat.Error)
+		}
+	}
+	if failed != 1 {
+		t.Errorf("got %d failed requests, wanted 1", failed)
+	}
+}
@@ -0,0 +1,202 @@
+package pewpew
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// HARFilter decides which entries of a HAR file become Targets
+type HARFilter struct {
+	//IncludeHosts, if not empty, keeps only requests to these hosts or their subdomains
+	IncludeHosts []string
+	//ExcludeHosts drops requests to these hosts or their subdomains
+	ExcludeHosts []string
+	//ExcludeStatic drops requests for images, fonts, stylesheets, scripts, and media
+	ExcludeStatic bool
+}
+
+// harFile is the subset of the HTTP Archive format needed to replay requests
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		Cookies  []harNameValue `json:"cookies"`
+		PostData *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harSkipHeaders are recorded headers that are set by the client for each
+// request instead of replayed
+var harSkipHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"transfer-encoding": true,
+}
+
+var staticExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".css": true, ".map": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true,
+	".ico": true, ".webp": true, ".avif": true, ".bmp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp4": true, ".webm": true, ".mp3": true, ".ogg": true, ".wav": true,
+}
+
+// ReadHAR creates a Target for each request recorded in a HAR file, in the
+// order they were recorded, keeping their method, URL, headers, cookies, and
+// body. Only the Target's URL and those Options are set, so the rest of the
+// Options still need to be filled in. Requests that aren't HTTP or HTTPS,
+// such as data URLs, are skipped.
+func ReadHAR(filename string, filter HARFilter) ([]Target, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR file: %w", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file %s: %w", filename, err)
+	}
+
+	var targets []Target
+	for i, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d of %s: %w", i+1, filename, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		if !filter.keep(u, entry.Response.Content.MimeType) {
+			continue
+		}
+		targets = append(targets, harTarget(entry))
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no requests in HAR file " + filename + " after filtering")
+	}
+	return targets, nil
+}
+
+// harTarget converts a HAR entry to a Target
+func harTarget(entry harEntry) Target {
+	req := entry.Request
+	t := Target{URL: req.URL}
+	t.Options.Method = req.Method
+	t.Options.RawHeaders = make(map[string]string)
+	for _, h := range req.Headers {
+		//HTTP/2 pseudo headers, like :authority, aren't real headers
+		if strings.HasPrefix(h.Name, ":") || harSkipHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		//repeated headers are combined, except cookies which are separated differently
+		sep := ", "
+		if strings.EqualFold(h.Name, "Cookie") {
+			sep = "; "
+		}
+		name := canonicalHeaderKey(t.Options.RawHeaders, h.Name)
+		if prev, ok := t.Options.RawHeaders[name]; ok {
+			t.Options.RawHeaders[name] = prev + sep + h.Value
+		} else {
+			t.Options.RawHeaders[name] = h.Value
+		}
+	}
+	//some tools record cookies without the Cookie header
+	if _, ok := t.Options.RawHeaders[canonicalHeaderKey(t.Options.RawHeaders, "Cookie")]; !ok && len(req.Cookies) > 0 {
+		cookies := make([]string, len(req.Cookies))
+		for i, c := range req.Cookies {
+			cookies[i] = c.Name + "=" + c.Value
+		}
+		t.Options.RawHeaders["Cookie"] = strings.Join(cookies, "; ")
+	}
+	if req.PostData != nil {
+		if req.PostData.Text != "" || len(req.PostData.Params) == 0 {
+			t.Options.Body = req.PostData.Text
+		} else {
+			form := url.Values{}
+			for _, p := range req.PostData.Params {
+				form.Add(p.Name, p.Value)
+			}
+			t.Options.Body = form.Encode()
+		}
+	}
+	return t
+}
+
+// canonicalHeaderKey returns the key already used in headers for the header
+// name, which can differ in case, or name if there is none
+func canonicalHeaderKey(headers map[string]string, name string) string {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// keep is whether a request to u, whose response had the MIME type mimeType, passes the filter
+func (f HARFilter) keep(u *url.URL, mimeType string) bool {
+	host := strings.ToLower(u.Hostname())
+	if len(f.IncludeHosts) > 0 && !matchesHost(host, f.IncludeHosts) {
+		return false
+	}
+	if matchesHost(host, f.ExcludeHosts) {
+		return false
+	}
+	if f.ExcludeStatic && isStatic(u, mimeType) {
+		return false
+	}
+	return true
+}
+
+// matchesHost is whether host is one of hosts, or a subdomain of one
+func matchesHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(h), "."))
+		if h == "" {
+			continue
+		}
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStatic is whether a request looks like it was for a static asset, by
+// either the MIME type of its response or the extension of its path
+func isStatic(u *url.URL, mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	switch {
+	case strings.HasPrefix(mimeType, "image/"), strings.HasPrefix(mimeType, "font/"),
+		strings.HasPrefix(mimeType, "audio/"), strings.HasPrefix(mimeType, "video/"),
+		mimeType == "text/css", strings.HasSuffix(mimeType, "javascript"):
+		return true
+	}
+	return staticExtensions[strings.ToLower(path.Ext(u.Path))]
+}
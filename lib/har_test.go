@@ -0,0 +1,144 @@
+package pewpew
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testHAR = `{"log": {"version": "1.2", "entries": [
+	{
+		"request": {"method": "GET", "url": "https://www.example.com/",
+			"headers": [
+				{"name": ":authority", "value": "www.example.com"},
+				{"name": "Accept", "value": "text/html,application/xhtml+xml"},
+				{"name": "User-Agent", "value": "Mozilla/5.0"},
+				{"name": "Cookie", "value": "a=1"},
+				{"name": "cookie", "value": "b=2"},
+				{"name": "Content-Length", "value": "0"}
+			],
+			"cookies": [{"name": "a", "value": "1"}, {"name": "b", "value": "2"}]},
+		"response": {"content": {"mimeType": "text/html; charset=utf-8"}}
+	},
+	{
+		"request": {"method": "GET", "url": "https://www.example.com/app.js?v=2", "headers": []},
+		"response": {"content": {"mimeType": "application/javascript"}}
+	},
+	{
+		"request": {"method": "GET", "url": "https://cdn.example.net/logo", "headers": []},
+		"response": {"content": {"mimeType": "image/png"}}
+	},
+	{
+		"request": {"method": "POST", "url": "https://api.example.com/login",
+			"headers": [{"name": "Content-Type", "value": "application/json"}],
+			"cookies": [{"name": "session", "value": "x"}],
+			"postData": {"mimeType": "application/json", "text": "{\"user\":\"a\"}"}},
+		"response": {"content": {"mimeType": "application/json"}}
+	},
+	{
+		"request": {"method": "POST", "url": "https://api.example.com/form", "headers": [],
+			"postData": {"mimeType": "application/x-www-form-urlencoded",
+				"params": [{"name": "q", "value": "a b"}, {"name": "n", "value": "1"}]}},
+		"response": {"content": {"mimeType": "text/html"}}
+	},
+	{
+		"request": {"method": "GET", "url": "data:image/png;base64,AAAA", "headers": []},
+		"response": {"content": {"mimeType": "image/png"}}
+	}
+]}}`
+
+func TestReadHAR(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    HARFilter
+		wantURLs  []string
+		expectErr bool
+	}{
+		{
+			name: "no filter",
+			wantURLs: []string{"https://www.example.com/", "https://www.example.com/app.js?v=2",
+				"https://cdn.example.net/logo", "https://api.example.com/login", "https://api.example.com/form"},
+		},
+		{
+			name:     "include hosts",
+			filter:   HARFilter{IncludeHosts: []string{"example.com"}},
+			wantURLs: []string{"https://www.example.com/", "https://www.example.com/app.js?v=2", "https://api.example.com/login", "https://api.example.com/form"},
+		},
+		{
+			name:     "exclude hosts",
+			filter:   HARFilter{ExcludeHosts: []string{"www.example.com", "CDN.example.net"}},
+			wantURLs: []string{"https://api.example.com/login", "https://api.example.com/form"},
+		},
+		{
+			name:     "exclude static",
+			filter:   HARFilter{ExcludeStatic: true},
+			wantURLs: []string{"https://www.example.com/", "https://api.example.com/login", "https://api.example.com/form"},
+		},
+		{
+			name:      "nothing left",
+			filter:    HARFilter{IncludeHosts: []string{"example.org"}},
+			expectErr: true,
+		},
+	}
+	filename := writeRequestFile(t, testHAR)
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			targets, err := ReadHAR(filename, tc.filter)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			urls := make([]string, len(targets))
+			for i, target := range targets {
+				urls[i] = target.URL
+			}
+			if len(urls) != len(tc.wantURLs) || (len(urls) > 0 && !reflect.DeepEqual(urls, tc.wantURLs)) {
+				t.Errorf("got URLs %v, wanted %v", urls, tc.wantURLs)
+			}
+		})
+	}
+
+	if _, err := ReadHAR("/nonexistent/session.har", HARFilter{}); err == nil {
+		t.Error("got no error for missing file")
+	}
+	if _, err := ReadHAR(writeRequestFile(t, `{"log": `), HARFilter{}); err == nil {
+		t.Error("got no error for invalid json")
+	}
+}
+
+func TestReadHARRequests(t *testing.T) {
+	targets, err := ReadHAR(writeRequestFile(t, testHAR), HARFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := targets[0].Options
+	wantHeaders := map[string]string{
+		"Accept":     "text/html,application/xhtml+xml",
+		"User-Agent": "Mozilla/5.0",
+		"Cookie":     "a=1; b=2",
+	}
+	if page.Method != "GET" || page.Body != "" || !reflect.DeepEqual(page.RawHeaders, wantHeaders) {
+		t.Errorf("got page method %q, body %q, headers %v, wanted GET, no body, headers %v", page.Method, page.Body, page.RawHeaders, wantHeaders)
+	}
+
+	//the request's headers are sent exactly as recorded
+	req, err := buildRequest(Target{URL: targets[0].URL, Options: page})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Accept") != wantHeaders["Accept"] || req.UserAgent() != "Mozilla/5.0" {
+		t.Errorf("got request headers %v", req.Header)
+	}
+
+	login := targets[3].Options
+	if login.Method != "POST" || login.Body != `{"user":"a"}` ||
+		login.RawHeaders["Content-Type"] != "application/json" || login.RawHeaders["Cookie"] != "session=x" {
+		t.Errorf("got login method %q, body %q, headers %v", login.Method, login.Body, login.RawHeaders)
+	}
+
+	form := targets[4].Options
+	if form.Body != "n=1&q=a+b" {
+		t.Errorf("got form body %q, wanted %q", form.Body, "n=1&q=a+b")
+	}
+}
@@ -0,0 +1,165 @@
+package pewpew
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// histogramSubBucketBits controls the precision of a latencyHistogram.
+// Each power of two range is split into 2^(histogramSubBucketBits-1) linear
+// sub-buckets, so recorded values are accurate to within 1/128 (~0.8%).
+const histogramSubBucketBits = 8
+
+const (
+	histogramSubBucketCount     = 1 << histogramSubBucketBits
+	histogramSubBucketHalfCount = histogramSubBucketCount / 2
+)
+
+// latencyHistogram is an HDR-style log-linear histogram of durations.
+// Memory is bounded regardless of how many values are recorded, because
+// values are grouped into buckets whose width grows with their magnitude.
+type latencyHistogram struct {
+	counts     []int64 //grown lazily up to the highest bucket recorded
+	totalCount int64
+	min        time.Duration
+	max        time.Duration
+}
+
+// histogramBin is a range of durations and how many recorded values fell into it
+type histogramBin struct {
+	from  time.Duration
+	to    time.Duration
+	count int64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{}
+}
+
+// bucketIndex finds which bucket a value belongs to
+func bucketIndex(v int64) int {
+	if v < histogramSubBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - histogramSubBucketBits
+	return histogramSubBucketCount + (shift-1)*histogramSubBucketHalfCount + int(v>>uint(shift)) - histogramSubBucketHalfCount
+}
+
+// bucketLowerBound is the smallest value that belongs to the bucket at idx
+func bucketLowerBound(idx int) int64 {
+	if idx < histogramSubBucketCount {
+		return int64(idx)
+	}
+	shift := (idx-histogramSubBucketCount)/histogramSubBucketHalfCount + 1
+	sub := (idx-histogramSubBucketCount)%histogramSubBucketHalfCount + histogramSubBucketHalfCount
+	return int64(sub) << uint(shift)
+}
+
+// record adds a single duration to the histogram. Negative durations are counted as zero.
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	idx := bucketIndex(int64(d))
+	if idx >= len(h.counts) {
+		grown := make([]int64, idx+1)
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	h.counts[idx]++
+	if h.totalCount == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.totalCount++
+}
+
+// merge adds all of the values recorded in other into h
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	if other == nil || other.totalCount == 0 {
+		return
+	}
+	if len(other.counts) > len(h.counts) {
+		grown := make([]int64, len(other.counts))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	for idx, count := range other.counts {
+		h.counts[idx] += count
+	}
+	if h.totalCount == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.totalCount += other.totalCount
+}
+
+// valueAtPercentile returns the recorded value at percentile p, from 0 to 100.
+// The result is the lower bound of the matching bucket, clamped to the
+// recorded min and max.
+func (h *latencyHistogram) valueAtPercentile(p float64) time.Duration {
+	if h == nil || h.totalCount == 0 {
+		return 0
+	}
+	if p > 100 {
+		p = 100
+	}
+	//nearest-rank method, 1-indexed
+	rank := int64(math.Ceil(p / 100 * float64(h.totalCount)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for idx, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			v := time.Duration(bucketLowerBound(idx))
+			if v < h.min {
+				return h.min
+			}
+			if v > h.max {
+				return h.max
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+// bins splits the range between min and max into n equal width bins
+func (h *latencyHistogram) bins(n int) []histogramBin {
+	if h == nil || h.totalCount == 0 || n <= 0 {
+		return nil
+	}
+	width := (h.max - h.min) / time.Duration(n)
+	if width <= 0 {
+		//every value is (nearly) the same, so a single bin covers them all
+		return []histogramBin{{from: h.min, to: h.max, count: h.totalCount}}
+	}
+	bins := make([]histogramBin, n)
+	for i := range bins {
+		bins[i].from = h.min + time.Duration(i)*width
+		bins[i].to = bins[i].from + width
+	}
+	bins[n-1].to = h.max
+	for idx, count := range h.counts {
+		if count == 0 {
+			continue
+		}
+		v := time.Duration(bucketLowerBound(idx))
+		if v < h.min {
+			v = h.min
+		}
+		i := int((v - h.min) / width)
+		if i >= n {
+			i = n - 1
+		}
+		bins[i].count += count
+	}
+	return bins
+}
@@ -0,0 +1,164 @@
+package pewpew
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// histogramOf creates a latencyHistogram with the durations recorded, in order
+func histogramOf(durations ...time.Duration) *latencyHistogram {
+	h := newLatencyHistogram()
+	for _, d := range durations {
+		h.record(d)
+	}
+	return h
+}
+
+func TestBucketIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int64
+	}{
+		{name: "zero", value: 0},
+		{name: "largest exact value", value: histogramSubBucketCount - 1},
+		{name: "smallest bucketed value", value: histogramSubBucketCount},
+		{name: "one millisecond", value: int64(time.Millisecond)},
+		{name: "one minute", value: int64(time.Minute)},
+		{name: "one day", value: int64(24 * time.Hour)},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			lower := bucketLowerBound(bucketIndex(tc.value))
+			if lower > tc.value {
+				t.Errorf("got lower bound %d above value %d", lower, tc.value)
+			}
+			//buckets are within 1/histogramSubBucketHalfCount of the value
+			if float64(tc.value-lower) > float64(tc.value)/histogramSubBucketHalfCount {
+				t.Errorf("got lower bound %d too far from value %d", lower, tc.value)
+			}
+		})
+	}
+}
+
+func TestValueAtPercentile(t *testing.T) {
+	tests := []struct {
+		name       string
+		h          *latencyHistogram
+		percentile float64
+		want       time.Duration
+	}{
+		{
+			name:       "nil histogram",
+			h:          nil,
+			percentile: 50,
+			want:       0,
+		},
+		{
+			name:       "empty histogram",
+			h:          histogramOf(),
+			percentile: 50,
+			want:       0,
+		},
+		{
+			name:       "single value",
+			h:          histogramOf(1000),
+			percentile: 99,
+			want:       1000,
+		},
+		{
+			name:       "median",
+			h:          histogramOf(100, 200, 300, 400, 500),
+			percentile: 50,
+			want:       300,
+		},
+		{
+			name:       "zero percentile is the min",
+			h:          histogramOf(100, 200, 300, 400, 500),
+			percentile: 0,
+			want:       100,
+		},
+		{
+			name:       "100 percentile is the max",
+			h:          histogramOf(100, 200, 300, 400, 500),
+			percentile: 100,
+			want:       500,
+		},
+		{
+			name:       "tail",
+			h:          histogramOf(1, 1, 1, 1, 1, 1, 1, 1, 1, 1<<30),
+			percentile: 99,
+			want:       1 << 30,
+		},
+		{
+			name:       "bucketed value is within precision",
+			h:          histogramOf(time.Millisecond, time.Second, 2*time.Second),
+			percentile: 50,
+			want:       998244352, //lower bound of the bucket containing one second
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := tc.h.valueAtPercentile(tc.percentile)
+			if got != tc.want {
+				t.Errorf("got: %d, wanted: %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	h := histogramOf(100, 200)
+	h.merge(histogramOf(time.Second))
+	h.merge(nil)
+	want := histogramOf(100, 200, time.Second)
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("got histogram: %+v, wanted: %+v", h, want)
+	}
+}
+
+func TestHistogramBins(t *testing.T) {
+	tests := []struct {
+		name      string
+		h         *latencyHistogram
+		n         int
+		wantCount []int64
+	}{
+		{
+			name:      "empty histogram",
+			h:         histogramOf(),
+			n:         10,
+			wantCount: []int64{},
+		},
+		{
+			name:      "identical values",
+			h:         histogramOf(100, 100, 100),
+			n:         10,
+			wantCount: []int64{3},
+		},
+		{
+			name:      "spread values",
+			h:         histogramOf(0, 100, 100, 200, 300, 300, 300, 400),
+			n:         4,
+			wantCount: []int64{1, 2, 1, 4},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bins := tc.h.bins(tc.n)
+			gotCount := make([]int64, len(bins))
+			for i, bin := range bins {
+				gotCount[i] = bin.count
+			}
+			if !reflect.DeepEqual(gotCount, tc.wantCount) {
+				t.Errorf("got counts: %v, wanted: %v", gotCount, tc.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package pewpew
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// metricsDurationBuckets are the upper bounds, in seconds, of the request duration histogram
+var metricsDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics is an Observer that keeps running totals of each Target's requests,
+// and serves them in the Prometheus text format, so a test can be watched live
+type Metrics struct {
+	lock    sync.Mutex
+	targets []*targetMetrics
+}
+
+// targetMetrics are the running totals of one Target
+type targetMetrics struct {
+	labels        string           //Prometheus labels identifying the Target
+	responses     map[int]int64    //count of each status code
+	errors        map[string]int64 //count of each class of error
+	dropped       int64
+	checkFailures int64
+	inFlight      int64
+	bytes         int64
+	//non-cumulative counts of each of metricsDurationBuckets, plus one for the rest
+	durationBuckets []int64
+	durationSum     float64 //seconds
+	durationCount   int64
+}
+
+// NewMetrics creates Metrics for the Targets of a test. Targets are identified
+// in the same order they are configured.
+func NewMetrics(targets []Target) *Metrics {
+	m := &Metrics{targets: make([]*targetMetrics, len(targets))}
+	for i, target := range targets {
+		m.targets[i] = &targetMetrics{
+			labels: fmt.Sprintf(`target="%d",method="%s",url="%s"`,
+				i+1, escapeLabelValue(target.Options.Method), escapeLabelValue(target.URL)),
+			responses:       make(map[int]int64),
+			errors:          make(map[string]int64),
+			durationBuckets: make([]int64, len(metricsDurationBuckets)+1),
+		}
+	}
+	return m
+}
+
+// RequestStarted implements Observer
+func (m *Metrics) RequestStarted(target int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if target < 0 || target >= len(m.targets) {
+		return
+	}
+	m.targets[target].inFlight++
+}
+
+// RequestFinished implements Observer
+func (m *Metrics) RequestFinished(target int, stat RequestStat) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if target < 0 || target >= len(m.targets) {
+		return
+	}
+	t := m.targets[target]
+	if stat.Dropped {
+		t.dropped++
+		return
+	}
+	t.inFlight--
+	if stat.Error != nil {
+		t.errors[ClassifyError(stat.Error)]++
+		return
+	}
+	t.responses[stat.StatusCode]++
+	if len(stat.CheckFailures) > 0 {
+		t.checkFailures++
+	}
+	t.bytes += int64(stat.DataTransferred)
+	seconds := stat.Duration.Seconds()
+	bucket := sort.SearchFloat64s(metricsDurationBuckets, seconds)
+	t.durationBuckets[bucket]++
+	t.durationSum += seconds
+	t.durationCount++
+}
+
+// ServeHTTP writes the current metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var b bytes.Buffer
+	m.write(&b)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	//the client has gone away if this fails, so there's no one to tell
+	_, _ = w.Write(b.Bytes())
+}
+
+func (m *Metrics) write(w io.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	header := func(name, metricType, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+	}
+
+	header("pewpew_responses_total", "counter", "Responses received, by status code.")
+	for _, t := range m.targets {
+		codes := make([]int, 0, len(t.responses))
+		for code := range t.responses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "pewpew_responses_total{%s,code=\"%d\"} %d\n", t.labels, code, t.responses[code])
+		}
+	}
+
+	header("pewpew_request_errors_total", "counter", "Requests that failed without a response, by class of error.")
+	for _, t := range m.targets {
+		classes := make([]string, 0, len(t.errors))
+		for class := range t.errors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(w, "pewpew_request_errors_total{%s,class=\"%s\"} %d\n", t.labels, class, t.errors[class])
+		}
+	}
+
+	header("pewpew_requests_dropped_total", "counter", "Benchmark requests not sent because the max in flight limit was reached.")
+	for _, t := range m.targets {
+		fmt.Fprintf(w, "pewpew_requests_dropped_total{%s} %d\n", t.labels, t.dropped)
+	}
+
+	header("pewpew_check_failures_total", "counter", "Responses that failed at least one response check.")
+	for _, t := range m.targets {
+		fmt.Fprintf(w, "pewpew_check_failures_total{%s} %d\n", t.labels, t.checkFailures)
+	}
+
+	header("pewpew_requests_in_flight", "gauge", "Requests sent that haven't finished yet.")
+	for _, t := range m.targets {
+		fmt.Fprintf(w, "pewpew_requests_in_flight{%s} %d\n", t.labels, t.inFlight)
+	}
+
+	header("pewpew_transferred_bytes_total", "counter", "Bytes sent and received, including headers.")
+	for _, t := range m.targets {
+		fmt.Fprintf(w, "pewpew_transferred_bytes_total{%s} %d\n", t.labels, t.bytes)
+	}
+
+	header("pewpew_request_duration_seconds", "histogram", "Time from sending a request to receiving the response headers.")
+	for _, t := range m.targets {
+		var cumulative int64
+		for i, upper := range metricsDurationBuckets {
+			cumulative += t.durationBuckets[i]
+			fmt.Fprintf(w, "pewpew_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				t.labels, strconv.FormatFloat(upper, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "pewpew_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", t.labels, t.durationCount)
+		fmt.Fprintf(w, "pewpew_request_duration_seconds_sum{%s} %s\n", t.labels, strconv.FormatFloat(t.durationSum, 'g', -1, 64))
+		fmt.Fprintf(w, "pewpew_request_duration_seconds_count{%s} %d\n", t.labels, t.durationCount)
+	}
+}
+
+// escapeLabelValue escapes s for use as a Prometheus label value
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// ClassifyError sorts a request error into a broad class: "timeout", "dns",
+// "connection_refused", "connection_reset", "tls", "canceled", or "other".
+// A nil error has no class, so is "".
+func ClassifyError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_reset"
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &certInvalidErr), errors.As(err, &recordHeaderErr):
+		return "tls"
+	default:
+		return "other"
+	}
+}
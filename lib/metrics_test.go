@@ -0,0 +1,112 @@
+package pewpew
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics([]Target{
+		{URL: "http://localhost/\"quoted\"", Options: TargetOptions{Method: "GET"}},
+		{URL: "http://localhost/b", Options: TargetOptions{Method: "POST"}},
+	})
+	m.RequestStarted(0)
+	m.RequestStarted(0)
+	m.RequestStarted(0)
+	m.RequestFinished(0, RequestStat{StatusCode: 200, Duration: 20 * time.Millisecond, DataTransferred: 100})
+	m.RequestFinished(0, RequestStat{StatusCode: 503, Duration: 2 * time.Second, DataTransferred: 50, CheckFailures: []string{"status not one of 200"}})
+	m.RequestFinished(1, RequestStat{Dropped: true})
+	m.RequestStarted(1)
+	m.RequestFinished(1, RequestStat{Error: &net.DNSError{Err: "no such host"}})
+	//out of range targets are ignored
+	m.RequestStarted(2)
+	m.RequestFinished(-1, RequestStat{StatusCode: 200})
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to get metrics: %s", err)
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("got content type %q, wanted text/plain", resp.Header.Get("Content-Type"))
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	target1 := `target="1",method="GET",url="http://localhost/\"quoted\""`
+	target2 := `target="2",method="POST",url="http://localhost/b"`
+	wantLines := []string{
+		"# TYPE pewpew_responses_total counter",
+		"pewpew_responses_total{" + target1 + `,code="200"} 1`,
+		"pewpew_responses_total{" + target1 + `,code="503"} 1`,
+		"pewpew_request_errors_total{" + target2 + `,class="dns"} 1`,
+		"pewpew_requests_dropped_total{" + target1 + "} 0",
+		"pewpew_requests_dropped_total{" + target2 + "} 1",
+		"pewpew_check_failures_total{" + target1 + "} 1",
+		"# TYPE pewpew_requests_in_flight gauge",
+		"pewpew_requests_in_flight{" + target1 + "} 1",
+		"pewpew_requests_in_flight{" + target2 + "} 0",
+		"pewpew_transferred_bytes_total{" + target1 + "} 150",
+		"# TYPE pewpew_request_duration_seconds histogram",
+		"pewpew_request_duration_seconds_bucket{" + target1 + `,le="0.01"} 0`,
+		"pewpew_request_duration_seconds_bucket{" + target1 + `,le="0.025"} 1`,
+		"pewpew_request_duration_seconds_bucket{" + target1 + `,le="2.5"} 2`,
+		"pewpew_request_duration_seconds_bucket{" + target1 + `,le="+Inf"} 2`,
+		"pewpew_request_duration_seconds_sum{" + target1 + "} 2.02",
+		"pewpew_request_duration_seconds_count{" + target1 + "} 2",
+		"pewpew_request_duration_seconds_count{" + target2 + "} 0",
+	}
+	lines := strings.Split(string(body), "\n")
+	for _, want := range wantLines {
+		found := false
+		for _, line := range lines {
+			if line == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("metrics are missing line %s", want)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "canceled", err: &url.Error{Op: "Get", URL: "http://localhost", Err: context.Canceled}, want: "canceled"},
+		{name: "deadline", err: &url.Error{Op: "Get", URL: "http://localhost", Err: context.DeadlineExceeded}, want: "timeout"},
+		{name: "dns", err: &url.Error{Op: "Get", URL: "http://nope", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "nope"}}}, want: "dns"},
+		{name: "refused", err: &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, want: "connection_refused"},
+		{name: "reset", err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}, want: "connection_reset"},
+		{name: "closed", err: fmt.Errorf("wrapped: %w", io.EOF), want: "connection_reset"},
+		{name: "tls", err: &url.Error{Op: "Get", URL: "https://localhost", Err: x509.UnknownAuthorityError{}}, want: "tls"},
+		{name: "other", err: errors.New("something else"), want: "other"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := ClassifyError(tc.err); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
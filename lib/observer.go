@@ -0,0 +1,26 @@
+package pewpew
+
+// Observer is notified about each request while a test is running, useful for
+// live monitoring. Its methods are called concurrently from many goroutines,
+// so must be safe for concurrent use and return quickly.
+type Observer interface {
+	//RequestStarted is called just before a request to the Target at index target is sent
+	RequestStarted(target int)
+	//RequestFinished is called with the RequestStat of each request to the Target at
+	//index target once it is done, including requests cut short by the test being
+	//stopped early, which are left out of the returned RequestStats. Dropped
+	//requests are finished without having been started.
+	RequestFinished(target int, stat RequestStat)
+}
+
+func notifyStarted(observers []Observer, target int) {
+	for _, o := range observers {
+		o.RequestStarted(target)
+	}
+}
+
+func notifyFinished(observers []Observer, target int, stat RequestStat) {
+	for _, o := range observers {
+		o.RequestFinished(target, stat)
+	}
+}
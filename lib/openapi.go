@@ -0,0 +1,784 @@
+package pewpew
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+
+	reggen "github.com/lucasjones/reggen"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// openAPIMaxRefs limits how many $refs are followed to resolve a schema
+const openAPIMaxRefs = 10
+
+// valueSyntax is how a generated value is written in the part of the request it's sent in
+type valueSyntax struct {
+	//json is whether values are JSON, rather than plain text
+	json bool
+	//escape is applied to literal values, such as examples
+	escape func(string) string
+	//chars are the characters that generated strings can have without being
+	//escaped, as pairs of the lowest and highest of each range
+	chars []rune
+}
+
+var (
+	pathSyntax = valueSyntax{
+		escape: url.PathEscape,
+		chars:  []rune{'-', '.', '0', ':', '@', 'Z', '_', '_', 'a', 'z', '~', '~'},
+	}
+	querySyntax = valueSyntax{
+		escape: url.QueryEscape,
+		chars:  []rune{'-', ':', '?', 'Z', '_', '_', 'a', 'z', '~', '~'},
+	}
+	headerSyntax = valueSyntax{
+		escape: func(s string) string { return s },
+		chars:  []rune{' ', '~'},
+	}
+	//cookie values can't have spaces, quotes, commas, semicolons or backslashes
+	cookieSyntax = valueSyntax{
+		escape: func(s string) string { return s },
+		chars:  []rune{'!', '!', '#', '+', '-', ':', '<', '[', ']', '~'},
+	}
+	//JSON strings can't have unescaped quotes or backslashes
+	jsonSyntax = valueSyntax{
+		json:  true,
+		chars: []rune{' ', '!', '#', '[', ']', '~'},
+	}
+)
+
+// OpenAPIOptions decide which operations of an OpenAPI document become Targets, and where they're sent
+type OpenAPIOptions struct {
+	//ServerURL is the base URL of the API. Defaults to the document's first server.
+	ServerURL string
+	//Operations, if not empty, keeps only operations with these operationIds
+	Operations []string
+	//Tags, if not empty, keeps only operations with at least one of these tags
+	Tags []string
+	//Methods, if not empty, keeps only operations with these HTTP methods
+	Methods []string
+}
+
+// openAPIDoc is the subset of an OpenAPI 3 document needed to build requests
+type openAPIDoc struct {
+	OpenAPI string `json:"openapi"`
+	Servers []struct {
+		URL       string `json:"url"`
+		Variables map[string]struct {
+			Default string `json:"default"`
+		} `json:"variables"`
+	} `json:"servers"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components struct {
+		Schemas       map[string]*openAPISchema      `json:"schemas"`
+		Parameters    map[string]*openAPIParameter   `json:"parameters"`
+		RequestBodies map[string]*openAPIRequestBody `json:"requestBodies"`
+	} `json:"components"`
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `json:"parameters"`
+	Get        *openAPIOperation   `json:"get"`
+	Put        *openAPIOperation   `json:"put"`
+	Post       *openAPIOperation   `json:"post"`
+	Delete     *openAPIOperation   `json:"delete"`
+	Options    *openAPIOperation   `json:"options"`
+	Head       *openAPIOperation   `json:"head"`
+	Patch      *openAPIOperation   `json:"patch"`
+	Trace      *openAPIOperation   `json:"trace"`
+}
+
+type openAPIOperation struct {
+	OperationID string              `json:"operationId"`
+	Tags        []string            `json:"tags"`
+	Parameters  []*openAPIParameter `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Ref      string                    `json:"$ref"`
+	Name     string                    `json:"name"`
+	In       string                    `json:"in"`
+	Required bool                      `json:"required"`
+	Schema   *openAPISchema            `json:"schema"`
+	Example  interface{}               `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+type openAPIRequestBody struct {
+	Ref     string                      `json:"$ref"`
+	Content map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema            `json:"schema"`
+	Example  interface{}               `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+type openAPIExample struct {
+	Value interface{} `json:"value"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Format     string                    `json:"format"`
+	Pattern    string                    `json:"pattern"`
+	Enum       []interface{}             `json:"enum"`
+	Example    interface{}               `json:"example"`
+	Default    interface{}               `json:"default"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+	ReadOnly   bool                      `json:"readOnly"`
+	MinLength  *int                      `json:"minLength"`
+	MaxLength  *int                      `json:"maxLength"`
+	Minimum    *float64                  `json:"minimum"`
+	Maximum    *float64                  `json:"maximum"`
+	AllOf      []*openAPISchema          `json:"allOf"`
+	OneOf      []*openAPISchema          `json:"oneOf"`
+	AnyOf      []*openAPISchema          `json:"anyOf"`
+}
+
+// ReadOpenAPI creates a Target for each operation of an OpenAPI 3 document, in
+// JSON or YAML. The URL and body of each Target are regular expressions, so
+// every request gets new values for the parameters and body fields that don't
+// have an example. Only the Target's URL, RegexURL and the Options for the
+// method, body and headers are set, so the rest of the Options still need to
+// be filled in.
+func ReadOpenAPI(filename string, options OpenAPIOptions) ([]Target, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document: %w", err)
+	}
+	doc, err := parseOpenAPI(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document %s: %w", filename, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s is not an OpenAPI 3 document", filename)
+	}
+
+	serverURL, err := doc.serverURL(options.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var targets []Target
+	for _, path := range paths {
+		item := doc.Paths[path]
+		operations := []struct {
+			method string
+			op     *openAPIOperation
+		}{
+			{"GET", item.Get}, {"PUT", item.Put}, {"POST", item.Post}, {"DELETE", item.Delete},
+			{"OPTIONS", item.Options}, {"HEAD", item.Head}, {"PATCH", item.Patch}, {"TRACE", item.Trace},
+		}
+		for _, o := range operations {
+			if o.op == nil || !options.keep(o.method, o.op) {
+				continue
+			}
+			target, err := doc.target(serverURL, path, o.method, item.Parameters, o.op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", o.method, path, err)
+			}
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no operations in OpenAPI document " + filename + " after filtering")
+	}
+	return targets, nil
+}
+
+// parseOpenAPI parses a JSON or YAML document. YAML is converted to JSON
+// first, so that both decode the same way.
+func parseOpenAPI(data []byte) (*openAPIDoc, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		converted, err := json.Marshal(yamlToJSON(raw))
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// yamlToJSON converts the maps decoded from YAML, which can have keys of
+// any type, to maps with string keys that can be encoded as JSON
+func yamlToJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = yamlToJSON(val)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = yamlToJSON(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// keep is whether an operation passes the options' filters
+func (o OpenAPIOptions) keep(method string, op *openAPIOperation) bool {
+	if len(o.Methods) > 0 && !containsFold(o.Methods, method) {
+		return false
+	}
+	if len(o.Operations) > 0 && !containsFold(o.Operations, op.OperationID) {
+		return false
+	}
+	if len(o.Tags) > 0 {
+		for _, tag := range op.Tags {
+			if containsFold(o.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverURL is the base URL of the API, without a trailing slash
+func (doc *openAPIDoc) serverURL(override string) (string, error) {
+	server := override
+	if server == "" && len(doc.Servers) > 0 {
+		server = doc.Servers[0].URL
+		for name, variable := range doc.Servers[0].Variables {
+			server = strings.Replace(server, "{"+name+"}", variable.Default, -1)
+		}
+	}
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		return "", fmt.Errorf("server URL %q is not an absolute http or https URL, so the server URL must be set", server)
+	}
+	return strings.TrimSuffix(server, "/"), nil
+}
+
+// target creates the Target of one operation. Path level parameters apply
+// unless the operation has a parameter with the same name and location.
+func (doc *openAPIDoc) target(serverURL, path, method string, pathParams []*openAPIParameter, op *openAPIOperation) (Target, error) {
+	params := make(map[string]*openAPIParameter)
+	var order []string
+	for _, p := range append(append([]*openAPIParameter{}, pathParams...), op.Parameters...) {
+		p, err := doc.resolveParameter(p)
+		if err != nil {
+			return Target{}, err
+		}
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+
+	//the path, with its parameters filled in
+	urlPattern := regexp.QuoteMeta(serverURL)
+	rest := path
+	for {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start < 0 || end < start {
+			break
+		}
+		urlPattern += regexp.QuoteMeta(rest[:start])
+		name := rest[start+1 : end]
+		value := "[a-z0-9]{8}"
+		if p, ok := params["path:"+name]; ok {
+			value = doc.parameterPattern(p, pathSyntax)
+		}
+		urlPattern += value
+		rest = rest[end+1:]
+	}
+	urlPattern += regexp.QuoteMeta(rest)
+
+	//query, header and cookie parameters are only sent if required or they have an example
+	var query, cookies []string
+	headers := make(map[string]string)
+	for _, key := range order {
+		p := params[key]
+		if !p.Required && !doc.hasExample(p) {
+			continue
+		}
+		switch p.In {
+		case "query":
+			query = append(query, regexp.QuoteMeta(url.QueryEscape(p.Name))+"="+doc.parameterPattern(p, querySyntax))
+		case "header", "cookie":
+			//headers aren't generated per request, so generate one value for every request now
+			vs := headerSyntax
+			if p.In == "cookie" {
+				vs = cookieSyntax
+			}
+			value, err := reggen.Generate(doc.parameterPattern(p, vs), 10)
+			if err != nil {
+				return Target{}, fmt.Errorf("failed to generate parameter %s: %w", p.Name, err)
+			}
+			if p.In == "header" {
+				headers[p.Name] = value
+			} else {
+				cookies = append(cookies, p.Name+"="+value)
+			}
+		}
+	}
+	if len(query) > 0 {
+		urlPattern += `\?` + strings.Join(query, "&")
+	}
+	if len(cookies) > 0 {
+		headers["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	t := Target{URL: urlPattern, RegexURL: true}
+	t.Options.Method = method
+	if op.RequestBody != nil {
+		body, err := doc.resolveRequestBody(op.RequestBody)
+		if err != nil {
+			return Target{}, err
+		}
+		if mediaType, pattern, ok := doc.bodyPattern(body); ok {
+			t.Options.Body = pattern
+			t.Options.RegexBody = true
+			headers["Content-Type"] = mediaType
+		}
+	}
+	if len(headers) > 0 {
+		t.Options.RawHeaders = headers
+	}
+	return t, nil
+}
+
+// bodyPattern creates a regular expression for the request body, preferring
+// JSON media types. Other media types need an example.
+func (doc *openAPIDoc) bodyPattern(body *openAPIRequestBody) (string, string, bool) {
+	mediaTypes := make([]string, 0, len(body.Content))
+	for mediaType := range body.Content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	for _, mediaType := range mediaTypes {
+		if !strings.Contains(mediaType, "json") {
+			continue
+		}
+		media := body.Content[mediaType]
+		if example, ok := mediaExample(media.Example, media.Examples); ok {
+			return mediaType, jsonLiteral(example), true
+		}
+		return mediaType, doc.schemaPattern(media.Schema, jsonSyntax, nil), true
+	}
+	for _, mediaType := range mediaTypes {
+		media := body.Content[mediaType]
+		if example, ok := mediaExample(media.Example, media.Examples); ok {
+			if s, isString := example.(string); isString {
+				return mediaType, regexp.QuoteMeta(s), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// mediaExample is the example, or else the first of the named examples
+func mediaExample(example interface{}, examples map[string]openAPIExample) (interface{}, bool) {
+	if example != nil {
+		return example, true
+	}
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if examples[name].Value != nil {
+			return examples[name].Value, true
+		}
+	}
+	return nil, false
+}
+
+// hasExample is whether a parameter has a fixed value to send
+func (doc *openAPIDoc) hasExample(p *openAPIParameter) bool {
+	if _, ok := mediaExample(p.Example, p.Examples); ok {
+		return true
+	}
+	s := doc.resolveSchema(p.Schema)
+	return s != nil && (s.Example != nil || s.Default != nil)
+}
+
+// parameterPattern creates a regular expression for a parameter's value,
+// written the way it is where the parameter is sent
+func (doc *openAPIDoc) parameterPattern(p *openAPIParameter, vs valueSyntax) string {
+	if example, ok := mediaExample(p.Example, p.Examples); ok {
+		return regexp.QuoteMeta(vs.escape(fmt.Sprint(example)))
+	}
+	s := doc.resolveSchema(p.Schema)
+	if s != nil && s.Type == "array" && s.Items != nil {
+		s = doc.resolveSchema(s.Items)
+	}
+	if s == nil {
+		return "[a-z0-9]{8}"
+	}
+	if value := firstNonNil(s.Example, s.Default); value != nil {
+		return regexp.QuoteMeta(vs.escape(fmt.Sprint(value)))
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = regexp.QuoteMeta(vs.escape(fmt.Sprint(v)))
+		}
+		return "(" + strings.Join(values, "|") + ")"
+	}
+	return doc.schemaPattern(s, vs, nil)
+}
+
+// schemaPattern creates a regular expression for values of a schema, written
+// as vs. refs are the $refs of the schemas it's nested in, so that schemas
+// that refer to themselves end.
+func (doc *openAPIDoc) schemaPattern(s *openAPISchema, vs valueSyntax, refs []string) string {
+	if s != nil && s.Ref != "" {
+		refs = append(refs[:len(refs):len(refs)], s.Ref)
+	}
+	s = doc.resolveSchema(s)
+	if s == nil {
+		if vs.json {
+			return "null"
+		}
+		return "[a-z0-9]{8}"
+	}
+	if len(s.AllOf) > 0 {
+		s = doc.mergeAllOf(s)
+	}
+	if len(s.OneOf) > 0 {
+		return doc.schemaPattern(s.OneOf[0], vs, refs)
+	}
+	if len(s.AnyOf) > 0 {
+		return doc.schemaPattern(s.AnyOf[0], vs, refs)
+	}
+
+	literal := func(v interface{}) string {
+		if vs.json {
+			return jsonLiteral(v)
+		}
+		return regexp.QuoteMeta(vs.escape(fmt.Sprint(v)))
+	}
+	if value := firstNonNil(s.Example, s.Default); value != nil {
+		return literal(value)
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = literal(v)
+		}
+		return "(" + strings.Join(values, "|") + ")"
+	}
+
+	schemaType := s.Type
+	if schemaType == "" {
+		switch {
+		case s.Properties != nil:
+			schemaType = "object"
+		case s.Items != nil:
+			schemaType = "array"
+		default:
+			schemaType = "string"
+		}
+	}
+	switch schemaType {
+	case "object":
+		if !vs.json {
+			return `\{\}`
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name, property := range s.Properties {
+			if expanding(property, refs) {
+				continue
+			}
+			if property := doc.resolveSchema(property); property != nil && property.ReadOnly {
+				continue
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = jsonLiteral(name) + ":" + doc.schemaPattern(s.Properties[name], vs, refs)
+		}
+		return `\{` + strings.Join(fields, ",") + `\}`
+	case "array":
+		if !vs.json {
+			return doc.schemaPattern(s.Items, vs, refs)
+		}
+		if expanding(s.Items, refs) {
+			return `\[\]`
+		}
+		return `\[` + doc.schemaPattern(s.Items, vs, refs) + `\]`
+	case "integer":
+		return numberPattern(s, false)
+	case "number":
+		return numberPattern(s, true)
+	case "boolean":
+		return "(true|false)"
+	default:
+		if vs.json {
+			return `"` + stringPattern(s, vs.chars) + `"`
+		}
+		return stringPattern(s, vs.chars)
+	}
+}
+
+// expanding is whether s refers to one of the schemas it's nested in
+func expanding(s *openAPISchema, refs []string) bool {
+	if s == nil || s.Ref == "" {
+		return false
+	}
+	for _, ref := range refs {
+		if ref == s.Ref {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeAllOf combines the properties of each schema of allOf
+func (doc *openAPIDoc) mergeAllOf(s *openAPISchema) *openAPISchema {
+	merged := *s
+	merged.AllOf = nil
+	merged.Properties = make(map[string]*openAPISchema)
+	for name, property := range s.Properties {
+		merged.Properties[name] = property
+	}
+	for _, part := range s.AllOf {
+		part = doc.resolveSchema(part)
+		if part == nil {
+			continue
+		}
+		if len(part.AllOf) > 0 {
+			part = doc.mergeAllOf(part)
+		}
+		for name, property := range part.Properties {
+			merged.Properties[name] = property
+		}
+		if merged.Type == "" {
+			merged.Type = part.Type
+		}
+	}
+	return &merged
+}
+
+// numberPattern creates a regular expression for a number. Numbers with a
+// minimum or maximum use the middle of their range, as it can't be expressed
+// as a regular expression.
+func numberPattern(s *openAPISchema, fraction bool) string {
+	if s.Minimum != nil || s.Maximum != nil {
+		var value float64
+		switch {
+		case s.Minimum != nil && s.Maximum != nil:
+			value = (*s.Minimum + *s.Maximum) / 2
+		case s.Minimum != nil:
+			value = *s.Minimum + 1
+		default:
+			value = *s.Maximum - 1
+		}
+		if !fraction {
+			return regexp.QuoteMeta(strconv.FormatInt(int64(value), 10))
+		}
+		return regexp.QuoteMeta(strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	if fraction {
+		return `[1-9][0-9]{0,2}\.[0-9]{2}`
+	}
+	return "[1-9][0-9]{0,2}"
+}
+
+// stringPattern creates a regular expression for a string, from its pattern,
+// format, or length. Strings only have chars, so a pattern or format that
+// needs other characters falls back to the length.
+func stringPattern(s *openAPISchema, chars []rune) string {
+	pattern := s.Pattern
+	const date = `20[0-9]{2}-(0[1-9]|1[0-2])-(0[1-9]|1[0-9]|2[0-8])`
+	switch {
+	case pattern != "":
+	case s.Format == "uuid":
+		pattern = "[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}"
+	case s.Format == "date":
+		pattern = date
+	case s.Format == "date-time":
+		pattern = date + "T([01][0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9]Z"
+	case s.Format == "email":
+		pattern = `[a-z]{8}@example\.com`
+	case s.Format == "uri", s.Format == "url":
+		pattern = `https://example\.com/[a-z]{8}`
+	case s.Format == "ipv4":
+		pattern = `10\.[0-9]{1,2}\.[0-9]{1,2}\.[1-9]`
+	}
+	if pattern != "" {
+		if limited, ok := limitPattern(pattern, chars); ok {
+			return limited
+		}
+	}
+	min, max := 8, 8
+	if s.MinLength != nil && *s.MinLength > min {
+		min, max = *s.MinLength, *s.MinLength
+	}
+	if s.MaxLength != nil && *s.MaxLength < max {
+		max = *s.MaxLength
+		if min > max {
+			min = max
+		}
+	}
+	if min == max {
+		return fmt.Sprintf("[a-z0-9]{%d}", min)
+	}
+	return fmt.Sprintf("[a-z0-9]{%d,%d}", min, max)
+}
+
+// limitPattern rewrites pattern so that it only generates chars, ranges of
+// characters as pairs of the lowest and highest of each. It's false if part of
+// the pattern can only generate other characters, or it isn't valid.
+func limitPattern(pattern string, chars []rune) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil || !limitRegexp(re, chars) {
+		return "", false
+	}
+	if re.Op == syntax.OpAlternate {
+		//grouped so it isn't split up by what's around it
+		return "(?:" + re.String() + ")", true
+	}
+	return re.String(), true
+}
+
+func limitRegexp(re *syntax.Regexp, chars []rune) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if !inRanges(r, chars) {
+				return false
+			}
+		}
+	case syntax.OpCharClass:
+		var limited []rune
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for j := 0; j+1 < len(chars); j += 2 {
+				lo, hi := re.Rune[i], re.Rune[i+1]
+				if chars[j] > lo {
+					lo = chars[j]
+				}
+				if chars[j+1] < hi {
+					hi = chars[j+1]
+				}
+				if lo <= hi {
+					limited = append(limited, lo, hi)
+				}
+			}
+		}
+		if len(limited) == 0 {
+			return false
+		}
+		re.Rune = limited
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		re.Op = syntax.OpCharClass
+		re.Rune = append([]rune{}, chars...)
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		//anchors don't generate anything, and can't be used within a URL or body
+		re.Op = syntax.OpEmptyMatch
+	}
+	for _, sub := range re.Sub {
+		if !limitRegexp(sub, chars) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRanges is whether r is in ranges, as pairs of the lowest and highest of each
+func inRanges(r rune, ranges []rune) bool {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if r >= ranges[i] && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonLiteral is a regular expression matching only v encoded as JSON
+func jsonLiteral(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return regexp.QuoteMeta(string(b))
+}
+
+func firstNonNil(values ...interface{}) interface{} {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// resolveSchema follows a schema's $ref to the components of the document
+func (doc *openAPIDoc) resolveSchema(s *openAPISchema) *openAPISchema {
+	for i := 0; s != nil && s.Ref != "" && i < openAPIMaxRefs; i++ {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func (doc *openAPIDoc) resolveParameter(p *openAPIParameter) (*openAPIParameter, error) {
+	if p == nil || p.Ref == "" {
+		return p, nil
+	}
+	resolved, ok := doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	if !ok || resolved == nil {
+		return nil, fmt.Errorf("unknown parameter %s", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (doc *openAPIDoc) resolveRequestBody(b *openAPIRequestBody) (*openAPIRequestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	resolved, ok := doc.Components.RequestBodies[strings.TrimPrefix(b.Ref, "#/components/requestBodies/")]
+	if !ok || resolved == nil {
+		return nil, fmt.Errorf("unknown request body %s", b.Ref)
+	}
+	return resolved, nil
+}
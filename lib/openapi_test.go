@@ -0,0 +1,280 @@
+package pewpew
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"testing"
+)
+
+const testOpenAPI = `openapi: 3.0.3
+info:
+  title: Pets
+  version: "1.0"
+servers:
+  - url: https://{env}.example.com/v1/
+    variables:
+      env:
+        default: api
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pets]
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, maximum: 100, minimum: 1}
+        - name: sort
+          in: query
+          required: true
+          schema: {type: string, enum: [name, age]}
+        - name: verbose
+          in: query
+          schema: {type: boolean}
+        - $ref: '#/components/parameters/Trace'
+    post:
+      operationId: createPet
+      tags: [pets, admin]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NewPet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema: {type: string, format: uuid}
+    get:
+      operationId: getPet
+      tags: [pets]
+    delete:
+      operationId: deletePet
+      tags: [admin]
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          example: a/b
+  /owners:
+    put:
+      operationId: putOwner
+      requestBody:
+        $ref: '#/components/requestBodies/Owner'
+components:
+  parameters:
+    Trace:
+      name: X-Trace
+      in: header
+      required: true
+      schema: {type: string, pattern: '^[0-9]{4}$'}
+  requestBodies:
+    Owner:
+      content:
+        application/json:
+          example: {name: Ann, pets: [1, 2]}
+  schemas:
+    NewPet:
+      allOf:
+        - $ref: '#/components/schemas/Named'
+        - type: object
+          properties:
+            id: {type: integer, readOnly: true}
+            born: {type: string, format: date}
+            weight: {type: number}
+            tags: {type: array, items: {type: string, maxLength: 3}}
+            kind: {type: string, example: cat}
+            parent: {$ref: '#/components/schemas/NewPet'}
+    Named:
+      type: object
+      properties:
+        name: {type: string, minLength: 10}
+`
+
+func TestReadOpenAPI(t *testing.T) {
+	filename := writeRequestFile(t, testOpenAPI)
+	tests := []struct {
+		name      string
+		options   OpenAPIOptions
+		want      []string //method and URL pattern of each target
+		expectErr bool
+	}{
+		{
+			name: "all operations",
+			want: []string{
+				"PUT " + `https://api\.example\.com/v1/owners`,
+				"GET " + `https://api\.example\.com/v1/pets\?sort=(name|age)`,
+				"POST " + `https://api\.example\.com/v1/pets`,
+				"GET " + `https://api\.example\.com/v1/pets/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}`,
+				"DELETE " + `https://api\.example\.com/v1/pets/a%2Fb`,
+			},
+		},
+		{
+			name:    "server override",
+			options: OpenAPIOptions{ServerURL: "http://localhost:8080", Operations: []string{"getpet"}},
+			want:    []string{"GET " + `http://localhost:8080/pets/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}`},
+		},
+		{
+			name:    "tags and methods",
+			options: OpenAPIOptions{Tags: []string{"admin"}, Methods: []string{"delete"}},
+			want:    []string{"DELETE " + `https://api\.example\.com/v1/pets/a%2Fb`},
+		},
+		{name: "nothing left", options: OpenAPIOptions{Tags: []string{"none"}}, expectErr: true},
+		{name: "relative server", options: OpenAPIOptions{ServerURL: "/v1"}, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			targets, err := ReadOpenAPI(filename, tc.options)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if len(targets) != len(tc.want) {
+				t.Fatalf("got %d targets, wanted %d", len(targets), len(tc.want))
+			}
+			for i, want := range tc.want {
+				got := targets[i].Options.Method + " " + targets[i].URL
+				if got != want {
+					t.Errorf("got target %d %s, wanted %s", i, got, want)
+				}
+				if !targets[i].RegexURL {
+					t.Errorf("got target %d without RegexURL", i)
+				}
+			}
+		})
+	}
+
+	if _, err := ReadOpenAPI("/nonexistent/openapi.yaml", OpenAPIOptions{}); err == nil {
+		t.Error("got no error for missing file")
+	}
+	if _, err := ReadOpenAPI(writeRequestFile(t, `{"swagger": "2.0", "paths": {}}`), OpenAPIOptions{}); err == nil {
+		t.Error("got no error for swagger 2 document")
+	}
+	if _, err := ReadOpenAPI(writeRequestFile(t, "openapi: [3"), OpenAPIOptions{}); err == nil {
+		t.Error("got no error for invalid yaml")
+	}
+}
+
+func TestReadOpenAPIRequests(t *testing.T) {
+	targets, err := ReadOpenAPI(writeRequestFile(t, testOpenAPI), OpenAPIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//header parameters get one value up front
+	list := targets[1]
+	if !regexp.MustCompile(`^[0-9]{4}$`).MatchString(list.Options.RawHeaders["X-Trace"]) {
+		t.Errorf("got X-Trace header %q", list.Options.RawHeaders["X-Trace"])
+	}
+
+	put := targets[0]
+	req, err := buildRequest(put)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	if string(body) != `{"name":"Ann","pets":[1,2]}` || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("got owner body %s, content type %q", body, req.Header.Get("Content-Type"))
+	}
+
+	//generated bodies are valid JSON that follows the schema
+	post := targets[2]
+	for i := 0; i < 20; i++ {
+		req, err := buildRequest(post)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		var pet struct {
+			ID     *int        `json:"id"`
+			Name   string      `json:"name"`
+			Born   string      `json:"born"`
+			Weight float64     `json:"weight"`
+			Tags   []string    `json:"tags"`
+			Kind   string      `json:"kind"`
+			Parent interface{} `json:"parent"`
+		}
+		if err := json.Unmarshal(body, &pet); err != nil {
+			t.Fatalf("got invalid JSON body %s: %v", body, err)
+		}
+		//read only and self referencing properties are left out
+		if pet.ID != nil || pet.Parent != nil || len(pet.Name) != 10 || len(pet.Tags) != 1 ||
+			len(pet.Tags[0]) != 3 || pet.Kind != "cat" || pet.Weight <= 0 ||
+			!regexp.MustCompile(`^20[0-9]{2}-[0-9]{2}-[0-9]{2}$`).MatchString(pet.Born) {
+			t.Errorf("got body %s", body)
+		}
+	}
+
+	req, err = buildRequest(targets[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^/v1/pets/[0-9a-f-]{36}$`).MatchString(req.URL.Path) {
+		t.Errorf("got path %s", req.URL.Path)
+	}
+}
+
+func TestReadOpenAPIPatterns(t *testing.T) {
+	//patterns with characters that would need escaping where they're sent
+	const doc = `openapi: 3.0.3
+servers:
+  - url: http://localhost/v1
+paths:
+  /files/{name}:
+    post:
+      parameters:
+        - {name: name, in: path, required: true, schema: {type: string, pattern: '^[a-z]{3}/[a-z]{3}$'}}
+        - {name: q, in: query, required: true, schema: {type: string, pattern: 'a b#c?d%'}}
+        - {name: r, in: query, required: true, schema: {type: string, pattern: '^.{5}$'}}
+        - {name: s, in: query, required: true, schema: {type: string, pattern: 'x|y'}}
+        - {name: session, in: cookie, required: true, schema: {type: string, pattern: '[a-z; ]{6}'}}
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                quoted: {type: string, pattern: '"[a-z]+"'}
+                note: {type: string, pattern: '[a-z"\\]{6}'}
+`
+	targets, err := ReadOpenAPI(writeRequestFile(t, doc), OpenAPIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("got %d targets, wanted 1", len(targets))
+	}
+	if cookie := targets[0].Options.RawHeaders["Cookie"]; !regexp.MustCompile(`^session=[a-z]{6}$`).MatchString(cookie) {
+		t.Errorf("got cookie %q", cookie)
+	}
+	for i := 0; i < 20; i++ {
+		req, err := buildRequest(targets[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		//characters that can't be in the path fall back to the length
+		if !regexp.MustCompile(`^/v1/files/[a-z0-9]{8}$`).MatchString(req.URL.Path) {
+			t.Errorf("got path %q", req.URL.Path)
+		}
+		query := req.URL.Query()
+		if len(query) != 3 || !regexp.MustCompile(`^[a-z0-9]{8}$`).MatchString(query.Get("q")) ||
+			!regexp.MustCompile(`^[-.0-9:?-Z_a-z~/]{5}$`).MatchString(query.Get("r")) ||
+			(query.Get("s") != "x" && query.Get("s") != "y") {
+			t.Errorf("got query %q", req.URL.RawQuery)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		var fields struct {
+			Quoted string `json:"quoted"`
+			Note   string `json:"note"`
+		}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatalf("got invalid JSON body %s: %v", body, err)
+		}
+		if !regexp.MustCompile(`^[a-z0-9]{8}$`).MatchString(fields.Quoted) || !regexp.MustCompile(`^[a-z]{6}$`).MatchString(fields.Note) {
+			t.Errorf("got body %s", body)
+		}
+	}
+}
@@ -6,12 +6,20 @@ import (
 	"io/ioutil"
 	"net/http"
 	"sort"
+	"strings"
 	"sync"
 
 	humanize "github.com/dustin/go-humanize"
 	color "github.com/fatih/color"
 )
 
+const (
+	//number of rows in the latency histogram
+	histogramBinCount = 10
+	//number of characters in the longest latency histogram bar
+	histogramBarWidth = 40
+)
+
 type printer struct {
 	//writeLock prevents concurrent messages from being interlaced
 	writeLock sync.Mutex
@@ -31,6 +39,35 @@ func CreateTextSummary(reqStatSummary RequestStatSummary) string {
 	summary += fmt.Sprintf("Mean RPS:             %.2f req/sec\n", reqStatSummary.avgRPS*1000000000)
 	summary += fmt.Sprintf("Total time:           %d ms\n", reqStatSummary.endTime.Sub(reqStatSummary.startTime).Nanoseconds()/1000000)
 
+	summary += "\nLatency Percentiles\n"
+	summary += fmt.Sprintf("50%%:     %d ms\n", reqStatSummary.p50Duration/1000000)
+	summary += fmt.Sprintf("90%%:     %d ms\n", reqStatSummary.p90Duration/1000000)
+	summary += fmt.Sprintf("95%%:     %d ms\n", reqStatSummary.p95Duration/1000000)
+	summary += fmt.Sprintf("99%%:     %d ms\n", reqStatSummary.p99Duration/1000000)
+	summary += fmt.Sprintf("99.9%%:   %d ms\n", reqStatSummary.p999Duration/1000000)
+	summary += fmt.Sprintf("Std dev: %d ms\n", reqStatSummary.stdDevDuration/1000000)
+
+	summary += createTextHistogram(reqStatSummary.latencies)
+
+	//only differs from the latencies above when requests were sent later than scheduled
+	if reqStatSummary.avgResponseTime != reqStatSummary.avgDuration || reqStatSummary.maxResponseTime != reqStatSummary.maxDuration {
+		summary += "\nResponse Time Percentiles (from scheduled send time)\n"
+		summary += fmt.Sprintf("Mean:    %d ms\n", reqStatSummary.avgResponseTime/1000000)
+		summary += fmt.Sprintf("50%%:     %d ms\n", reqStatSummary.responseTimes.valueAtPercentile(50)/1000000)
+		summary += fmt.Sprintf("90%%:     %d ms\n", reqStatSummary.responseTimes.valueAtPercentile(90)/1000000)
+		summary += fmt.Sprintf("95%%:     %d ms\n", reqStatSummary.responseTimes.valueAtPercentile(95)/1000000)
+		summary += fmt.Sprintf("99%%:     %d ms\n", reqStatSummary.responseTimes.valueAtPercentile(99)/1000000)
+		summary += fmt.Sprintf("99.9%%:   %d ms\n", reqStatSummary.responseTimes.valueAtPercentile(99.9)/1000000)
+		summary += fmt.Sprintf("Max:     %d ms\n", reqStatSummary.maxResponseTime/1000000)
+	}
+
+	summary += "\nTiming Breakdown (mean)\n"
+	summary += fmt.Sprintf("DNS lookup:       %.2f ms\n", float64(reqStatSummary.avgDNSDuration)/1000000)
+	summary += fmt.Sprintf("TCP connect:      %.2f ms\n", float64(reqStatSummary.avgConnectDuration)/1000000)
+	summary += fmt.Sprintf("TLS handshake:    %.2f ms\n", float64(reqStatSummary.avgTLSDuration)/1000000)
+	summary += fmt.Sprintf("Time to 1st byte: %.2f ms\n", float64(reqStatSummary.avgTimeToFirstByte)/1000000)
+	summary += fmt.Sprintf("Body transfer:    %.2f ms\n", float64(reqStatSummary.avgTransferDuration)/1000000)
+
 	summary += "\nData Transferred\n"
 	summary += fmt.Sprintf("Mean query:      %s\n", humanize.Bytes(uint64(reqStatSummary.avgDataTransferred)))
 	summary += fmt.Sprintf("Largest query:   %s\n", humanize.Bytes(uint64(reqStatSummary.maxDataTransferred)))
@@ -62,14 +99,57 @@ func CreateTextSummary(reqStatSummary RequestStatSummary) string {
 	}
 
 	summary += fmt.Sprintf("\nErrors\nFailed requests: %d\n", reqStatSummary.errorCount)
+	if reqStatSummary.droppedCount > 0 {
+		summary += fmt.Sprintf("Dropped:         %d requests (client at max in flight)\n", reqStatSummary.droppedCount)
+	}
+	summary += fmt.Sprintf("Failed checks:   %d responses\n", reqStatSummary.checkFailedCount)
+	//sort the check failures so the output is stable
+	var failures []string
+	for failure := range reqStatSummary.checkFailures {
+		failures = append(failures, failure)
+	}
+	sort.Strings(failures)
+	for _, failure := range failures {
+		summary += fmt.Sprintf("  %s: %d\n", failure, reqStatSummary.checkFailures[failure])
+	}
 	return summary
 }
 
+// createTextHistogram draws the latency distribution as horizontal ASCII bars
+func createTextHistogram(h *latencyHistogram) string {
+	bins := h.bins(histogramBinCount)
+	if len(bins) == 0 {
+		return ""
+	}
+	var maxCount int64
+	for _, bin := range bins {
+		if bin.count > maxCount {
+			maxCount = bin.count
+		}
+	}
+	histogram := "\nLatency Histogram\n"
+	for _, bin := range bins {
+		barLen := int(bin.count * histogramBarWidth / maxCount)
+		histogram += fmt.Sprintf("%8.1f ms [%7d] |%s\n",
+			float64(bin.from)/1000000,
+			bin.count,
+			strings.Repeat("#", barLen))
+	}
+	return histogram
+}
+
 // print colored single line stats per RequestStat
 func (p *printer) printStat(stat RequestStat) {
 	p.writeLock.Lock()
 	defer p.writeLock.Unlock()
 
+	if stat.Dropped {
+		color.Set(color.FgYellow)
+		fmt.Fprintln(p.output, "Dropped request, too many in flight: "+stat.Method+" "+stat.URL)
+		color.Unset()
+		return
+	}
+
 	if stat.Error != nil {
 		color.Set(color.FgRed)
 		fmt.Fprintln(p.output, "Failed to make request: "+stat.Error.Error())
@@ -96,6 +176,12 @@ func (p *printer) printStat(stat RequestStat) {
 		stat.Method,
 		stat.URL)
 	color.Unset()
+
+	if len(stat.CheckFailures) > 0 {
+		color.Set(color.FgRed)
+		fmt.Fprintln(p.output, "  Failed checks: "+strings.Join(stat.CheckFailures, "; "))
+		color.Unset()
+	}
 }
 
 // print tons of info about the request, response and response body
@@ -33,6 +33,45 @@ func TestCreateTextSummary(t *testing.T) {
 				totalDataTransferred: 123456,
 			},
 		},
+		{
+			name: "valid summary with latency histogram",
+			s: RequestStatSummary{
+				avgDuration: 1500 * time.Millisecond,
+				minDuration: time.Millisecond,
+				maxDuration: 3 * time.Second,
+				p50Duration: time.Second,
+				p99Duration: 3 * time.Second,
+				statusCodes: map[int]int{200: 4},
+				latencies:   histogramOf(time.Millisecond, time.Second, 2*time.Second, 3*time.Second),
+			},
+		},
+		{
+			name: "valid summary with failed checks",
+			s: RequestStatSummary{
+				statusCodes:      map[int]int{200: 3},
+				checkFailedCount: 2,
+				checkFailures:    map[string]int{"missing header ETag": 2, "body is not valid JSON": 1},
+			},
+		},
+		{
+			name: "valid summary with requests sent late",
+			s: RequestStatSummary{
+				avgDuration:     time.Second,
+				maxDuration:     time.Second,
+				avgResponseTime: 2 * time.Second,
+				maxResponseTime: 3 * time.Second,
+				statusCodes:     map[int]int{200: 2},
+				latencies:       histogramOf(time.Second, time.Second),
+				responseTimes:   histogramOf(time.Second, 3*time.Second),
+			},
+		},
+		{
+			name: "valid summary with dropped requests",
+			s: RequestStatSummary{
+				statusCodes:  map[int]int{200: 3},
+				droppedCount: 5,
+			},
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
@@ -56,6 +95,14 @@ func TestPrintStat(t *testing.T) {
 			name: "status code 100",
 			r:    RequestStat{StatusCode: 100},
 		},
+		{
+			name: "dropped",
+			r:    RequestStat{Method: "GET", URL: "http://localhost", Dropped: true},
+		},
+		{
+			name: "failed checks",
+			r:    RequestStat{StatusCode: 200, CheckFailures: []string{"missing header ETag", "body is not valid JSON"}},
+		},
 		{
 			name: "status code 200",
 			r:    RequestStat{StatusCode: 200},
@@ -0,0 +1,248 @@
+package pewpew
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Request orders control which request of a Target's RequestFile is sent next
+const (
+	//RequestOrderRoundRobin sends the requests in order, starting over after the last one
+	RequestOrderRoundRobin = "round-robin"
+	//RequestOrderOrdered sends each request once, in order, then stops
+	RequestOrderOrdered = "ordered"
+	//RequestOrderRandom picks requests at random, in proportion to their Weight
+	RequestOrderRandom = "random"
+)
+
+// ReplayRequest is one request of a RequestFile, which has one JSON object per line
+type ReplayRequest struct {
+	//Method defaults to the Target's Method
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	//Weight is how often the request is picked in random order,
+	//relative to the other requests. Defaults to 1.
+	Weight float64 `json:"weight"`
+	//Timestamp is when the request was captured. If every request has one,
+	//they are sent in order of Timestamp instead of the order of the file.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// requestSource creates the requests to send to a Target
+type requestSource interface {
+	//next creates the next request, or returns false once there are none left
+	next() (http.Request, bool, error)
+}
+
+// newRequestSource creates the requestSource of the Target, and checks that
+// its requests can be built
+func newRequestSource(target Target) (requestSource, error) {
+	var tmpl *requestTemplate
+	if target.Template || target.DataFile != "" {
+		var err error
+		if tmpl, err = newRequestTemplate(target); err != nil {
+			return nil, err
+		}
+	}
+	if target.RequestFile == "" {
+		//attempt to build one request - if passes, the rest should too
+		s := &templateSource{target: target, tmpl: tmpl}
+		if _, _, err := s.build(); err != nil {
+			return nil, err
+		}
+		if tmpl != nil {
+			tmpl.reset()
+		}
+		return s, nil
+	}
+	requests, err := readRequestFile(target.RequestFile)
+	if err != nil {
+		return nil, err
+	}
+	s := &replaySource{target: target, tmpl: tmpl, requests: requests, order: target.RequestOrder}
+	if s.order == "" {
+		s.order = RequestOrderRoundRobin
+	}
+	for i := range requests {
+		if _, _, err := s.build(i); err != nil {
+			return nil, fmt.Errorf("request %d of %s: %w", i+1, target.RequestFile, err)
+		}
+		if tmpl != nil {
+			tmpl.reset()
+		}
+	}
+	if s.order == RequestOrderRandom {
+		s.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+		s.cumulativeWeights = make([]float64, len(requests))
+		total := 0.0
+		for i, r := range requests {
+			weight := r.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			total += weight
+			s.cumulativeWeights[i] = total
+		}
+	}
+	return s, nil
+}
+
+// templateSource builds every request from the Target's URL and Options
+type templateSource struct {
+	target Target
+	//tmpl renders the Target for each request, if it's a template
+	tmpl *requestTemplate
+}
+
+func (s *templateSource) next() (http.Request, bool, error) {
+	return s.build()
+}
+
+func (s *templateSource) build() (http.Request, bool, error) {
+	t := s.target
+	if s.tmpl != nil {
+		var ok bool
+		var err error
+		if t, ok, err = s.tmpl.render(t); !ok || err != nil {
+			return http.Request{}, ok, err
+		}
+	}
+	req, err := buildRequest(t)
+	return req, true, err
+}
+
+// replaySource sends the requests of a Target's RequestFile
+type replaySource struct {
+	target   Target
+	tmpl     *requestTemplate
+	requests []ReplayRequest
+	order    string
+	//index of the next request in ordered and round-robin order
+	pos int
+	//for random order
+	rng               *rand.Rand
+	cumulativeWeights []float64
+}
+
+func (s *replaySource) next() (http.Request, bool, error) {
+	var i int
+	switch s.order {
+	case RequestOrderOrdered:
+		if s.pos >= len(s.requests) {
+			return http.Request{}, false, nil
+		}
+		i = s.pos
+		s.pos++
+	case RequestOrderRandom:
+		pick := s.rng.Float64() * s.cumulativeWeights[len(s.cumulativeWeights)-1]
+		i = sort.SearchFloat64s(s.cumulativeWeights, pick)
+		//pick can't equal the total, but guard against rounding
+		if i >= len(s.requests) {
+			i = len(s.requests) - 1
+		}
+	default:
+		i = s.pos
+		s.pos = (s.pos + 1) % len(s.requests)
+	}
+	return s.build(i)
+}
+
+// build creates the request at index i, using the Target's Options for
+// everything the ReplayRequest doesn't set. It returns false once the
+// Target's DataFile has no rows left.
+func (s *replaySource) build(i int) (http.Request, bool, error) {
+	r := s.requests[i]
+	t := s.target
+	t.URL = r.URL
+	t.RegexURL = false
+	if r.Method != "" {
+		t.Options.Method = r.Method
+	}
+	t.Options.Body = r.Body
+	t.Options.RegexBody = false
+	t.Options.BodyFilename = ""
+	if len(r.Headers) > 0 {
+		t.Options.RawHeaders = make(map[string]string, len(s.target.Options.RawHeaders)+len(r.Headers))
+		for key, val := range s.target.Options.RawHeaders {
+			t.Options.RawHeaders[key] = val
+		}
+		for key, val := range r.Headers {
+			t.Options.RawHeaders[key] = val
+		}
+	}
+	if s.tmpl != nil {
+		var ok bool
+		var err error
+		if t, ok, err = s.tmpl.render(t); !ok || err != nil {
+			return http.Request{}, ok, err
+		}
+	}
+	req, err := buildRequest(t)
+	return req, true, err
+}
+
+// readRequestFile reads the ReplayRequests of a JSON Lines file. Blank lines are skipped.
+func readRequestFile(filename string) ([]ReplayRequest, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request file: %w", err)
+	}
+	defer file.Close()
+
+	var requests []ReplayRequest
+	scanner := bufio.NewScanner(file)
+	//allow for large request bodies
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	allTimestamped := true
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var r ReplayRequest
+		if err := json.Unmarshal([]byte(text), &r); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, line, err)
+		}
+		if r.URL == "" {
+			return nil, fmt.Errorf("%s line %d: empty url", filename, line)
+		}
+		if r.Weight < 0 {
+			return nil, fmt.Errorf("%s line %d: weight cannot be negative", filename, line)
+		}
+		if r.Timestamp.IsZero() {
+			allTimestamped = false
+		}
+		requests = append(requests, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read request file: %w", err)
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("no requests in request file " + filename)
+	}
+	if allTimestamped {
+		sort.SliceStable(requests, func(i, j int) bool {
+			return requests[i].Timestamp.Before(requests[j].Timestamp)
+		})
+	}
+	return requests, nil
+}
+
+func validateRequestOrder(order string) error {
+	switch order {
+	case "", RequestOrderRoundRobin, RequestOrderOrdered, RequestOrderRandom:
+		return nil
+	default:
+		return fmt.Errorf("unknown request order %q, must be one of %s, %s, %s", order, RequestOrderRoundRobin, RequestOrderOrdered, RequestOrderRandom)
+	}
+}
@@ -0,0 +1,211 @@
+package pewpew
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeRequestFile writes contents to a request file in a temporary directory
+func writeRequestFile(t *testing.T, contents string) string {
+	return writeTempFile(t, "requests.jsonl", contents)
+}
+
+// writeTempFile writes contents to a file named name in a temporary directory
+func writeTempFile(t *testing.T, name, contents string) string {
+	dir, err := ioutil.TempDir("", "pewpew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadRequestFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		contents  string
+		wantURLs  []string
+		expectErr bool
+	}{
+		{
+			name:     "file order",
+			contents: "{\"url\": \"http://localhost/a\"}\n\n{\"url\": \"http://localhost/b\", \"method\": \"POST\", \"body\": \"{}\"}\n",
+			wantURLs: []string{"http://localhost/a", "http://localhost/b"},
+		},
+		{
+			name: "timestamp order",
+			contents: `{"url": "http://localhost/a", "timestamp": "2021-01-01T00:00:02Z"}
+{"url": "http://localhost/b", "timestamp": "2021-01-01T00:00:01Z"}`,
+			wantURLs: []string{"http://localhost/b", "http://localhost/a"},
+		},
+		{
+			name: "file order when some are missing timestamps",
+			contents: `{"url": "http://localhost/a", "timestamp": "2021-01-01T00:00:02Z"}
+{"url": "http://localhost/b"}`,
+			wantURLs: []string{"http://localhost/a", "http://localhost/b"},
+		},
+		{name: "empty", contents: "\n", expectErr: true},
+		{name: "invalid json", contents: `{"url": `, expectErr: true},
+		{name: "missing url", contents: `{"method": "GET"}`, expectErr: true},
+		{name: "negative weight", contents: `{"url": "http://localhost", "weight": -1}`, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			requests, err := readRequestFile(writeRequestFile(t, tc.contents))
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if len(requests) != len(tc.wantURLs) {
+				t.Fatalf("got %d requests, wanted %d", len(requests), len(tc.wantURLs))
+			}
+			for i, want := range tc.wantURLs {
+				if requests[i].URL != want {
+					t.Errorf("got request %d URL %s, wanted %s", i, requests[i].URL, want)
+				}
+			}
+		})
+	}
+	if _, err := readRequestFile("/nonexistent/requests.jsonl"); err == nil {
+		t.Error("got no error for missing file")
+	}
+}
+
+func TestReplaySource(t *testing.T) {
+	contents := `{"url": "http://localhost/a", "headers": {"X-Test": "a", "Host": "example.com"}}
+{"url": "http://localhost/b", "method": "POST", "body": "b", "weight": 1000}
+`
+	target := Target{Options: TargetOptions{Method: "GET", Headers: "X-Test:default", UserAgent: "pewpew"}}
+	tests := []struct {
+		name  string
+		order string
+		//URL paths of the first requests, or empty for any
+		wantPaths []string
+		//whether the source runs out after wantPaths
+		wantDone bool
+	}{
+		{name: "default is round robin", order: "", wantPaths: []string{"/a", "/b", "/a", "/b", "/a"}},
+		{name: "round robin", order: RequestOrderRoundRobin, wantPaths: []string{"/a", "/b", "/a"}},
+		{name: "ordered", order: RequestOrderOrdered, wantPaths: []string{"/a", "/b"}, wantDone: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			target := target
+			target.RequestFile = writeRequestFile(t, contents)
+			target.RequestOrder = tc.order
+			source, err := newRequestSource(target)
+			if err != nil {
+				t.Fatalf("got error: %s", err)
+			}
+			for i, want := range tc.wantPaths {
+				req, ok, err := source.next()
+				if !ok || err != nil {
+					t.Fatalf("request %d: got ok %t, error %v", i, ok, err)
+				}
+				if req.URL.Path != want {
+					t.Errorf("request %d: got path %s, wanted %s", i, req.URL.Path, want)
+				}
+			}
+			_, ok, _ := source.next()
+			if ok == tc.wantDone {
+				t.Errorf("got more requests %t, wanted %t", ok, !tc.wantDone)
+			}
+		})
+	}
+
+	t.Run("request details", func(t *testing.T) {
+		t.Parallel()
+		target := target
+		target.RequestFile = writeRequestFile(t, contents)
+		target.RequestOrder = RequestOrderOrdered
+		source, err := newRequestSource(target)
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		first, _, _ := source.next()
+		if first.Method != "GET" || first.Header.Get("X-Test") != "a" || first.Host != "example.com" || first.Header.Get("User-Agent") != "pewpew" {
+			t.Errorf("got request %s %s with host %s and headers %v", first.Method, first.URL, first.Host, first.Header)
+		}
+		second, _, _ := source.next()
+		body, _ := ioutil.ReadAll(second.Body)
+		if second.Method != "POST" || string(body) != "b" || second.Header.Get("X-Test") != "default" {
+			t.Errorf("got request %s %s with body %q and headers %v", second.Method, second.URL, body, second.Header)
+		}
+	})
+
+	t.Run("random is weighted", func(t *testing.T) {
+		t.Parallel()
+		target := target
+		target.RequestFile = writeRequestFile(t, contents)
+		target.RequestOrder = RequestOrderRandom
+		source, err := newRequestSource(target)
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+		heavy := 0
+		for i := 0; i < 1000; i++ {
+			req, _, _ := source.next()
+			if req.URL.Path == "/b" {
+				heavy++
+			}
+		}
+		//expect about 999 of 1000
+		if heavy < 950 {
+			t.Errorf("got %d of 1000 requests for the request weighted 1000 to 1", heavy)
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		t.Parallel()
+		target := target
+		target.RequestFile = writeRequestFile(t, `{"url": "http://"}`)
+		if _, err := newRequestSource(target); err == nil {
+			t.Error("got no error for a request without a host")
+		}
+	})
+}
+
+func TestRunStressRequestFile(t *testing.T) {
+	paths := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+	}))
+	defer server.Close()
+
+	s := StressConfig{
+		Count:       10,
+		Concurrency: 1,
+		Quiet:       true,
+		Targets: []Target{{
+			RequestFile:  writeRequestFile(t, `{"url": "`+server.URL+`/a"}`+"\n"+`{"url": "`+server.URL+`/b"}`),
+			RequestOrder: RequestOrderOrdered,
+			Options:      TargetOptions{Method: "GET"},
+		}},
+	}
+	stats, err := RunStress(s, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	if len(stats[0]) != 2 {
+		t.Errorf("got %d requests, wanted each of the 2 in the file once", len(stats[0]))
+	}
+	close(paths)
+	got := []string{}
+	for path := range paths {
+		got = append(got, path)
+	}
+	if len(got) != 2 || got[0] != "/a" || got[1] != "/b" {
+		t.Errorf("got requests to %v, wanted /a then /b", got)
+	}
+}
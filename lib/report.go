@@ -0,0 +1,339 @@
+package pewpew
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+)
+
+// Report is everything shown in an HTML report of a finished test
+type Report struct {
+	//Title is shown at the top of the report, such as "Stress test"
+	Title string
+	//Settings are the human friendly configuration of the test, shown in order
+	Settings []ReportSetting
+	Targets  []Target
+	//TargetRequestStats are the results of each Target, in the same order as Targets
+	TargetRequestStats [][]RequestStat
+	//Interval is the length of each point of the throughput and latency over time charts
+	Interval time.Duration
+}
+
+// ReportSetting is a single named configuration value shown in a Report
+type ReportSetting struct {
+	Name  string
+	Value string
+}
+
+// chart dimensions, in SVG user units
+const (
+	chartWidth   = 640
+	chartHeight  = 220
+	chartPadding = 40
+)
+
+// reportSection is the summary and charts of one target, or all of them combined
+type reportSection struct {
+	Title       string
+	Rows        []ReportSetting
+	StatusCodes []reportStatusCode
+	Histogram   template.HTML
+	Percentiles template.HTML
+	Throughput  template.HTML
+	Latency     template.HTML
+}
+
+type reportStatusCode struct {
+	Code    string
+	Count   int
+	Percent float64
+}
+
+// percentiles plotted in the percentile chart
+var reportPercentiles = []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 95, 99, 99.9, 100}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1400px; color: #222; }
+h1, h2, h3 { font-weight: 600; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 0.2em; margin-top: 2em; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+td, th { padding: 0.2em 1em 0.2em 0; text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.charts { display: flex; flex-wrap: wrap; gap: 1em; }
+.chart { border: 1px solid #eee; padding: 0.5em; }
+.chart h3 { margin: 0 0 0.3em; font-size: 1em; }
+svg text { font-size: 11px; fill: #555; }
+.bar { fill: #4a7fb5; }
+.axis { stroke: #999; stroke-width: 1; }
+.line { fill: none; stroke-width: 2; }
+.empty { color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Generated}}</p>
+<h2>Configuration</h2>
+<table>
+{{range .Settings}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{range .Sections}}
+<h2>{{.Title}}</h2>
+<table>
+{{range .Rows}}<tr><th>{{.Name}}</th><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+<h3>Status codes</h3>
+{{if .StatusCodes}}<table>
+<tr><th>Code</th><th>Count</th><th>Share</th></tr>
+{{range .StatusCodes}}<tr><td>{{.Code}}</td><td class="num">{{.Count}}</td><td class="num">{{printf "%.2f" .Percent}}%</td></tr>
+{{end}}</table>{{else}}<p class="empty">No responses</p>{{end}}
+<div class="charts">
+<div class="chart"><h3>Latency histogram</h3>{{.Histogram}}</div>
+<div class="chart"><h3>Latency by percentile</h3>{{.Percentiles}}</div>
+<div class="chart"><h3>Throughput over time</h3>{{.Throughput}}</div>
+<div class="chart"><h3>Latency over time</h3>{{.Latency}}</div>
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes the Report to w as a single HTML page
+// with no external assets, with charts drawn as inline SVG
+func WriteHTMLReport(w io.Writer, r Report) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	var sections []reportSection
+	var globalStats []RequestStat
+	for idx, target := range r.Targets {
+		var stats []RequestStat
+		if idx < len(r.TargetRequestStats) {
+			stats = r.TargetRequestStats[idx]
+		}
+		globalStats = append(globalStats, stats...)
+		if len(r.Targets) > 1 {
+			title := fmt.Sprintf("Target %d: %s %s", idx+1, target.Options.Method, target.URL)
+			sections = append(sections, newReportSection(title, stats, interval))
+		}
+	}
+	title := "Summary"
+	if len(r.Targets) > 1 {
+		title = "Global"
+	}
+	sections = append(sections, newReportSection(title, globalStats, interval))
+
+	return reportTemplate.Execute(w, struct {
+		Title     string
+		Generated string
+		Settings  []ReportSetting
+		Sections  []reportSection
+	}{
+		Title:     r.Title,
+		Generated: time.Now().Format(time.RFC1123),
+		Settings:  r.Settings,
+		Sections:  sections,
+	})
+}
+
+func newReportSection(title string, stats []RequestStat, interval time.Duration) reportSection {
+	s := CreateRequestsStats(stats)
+	section := reportSection{
+		Title: title,
+		Rows: []ReportSetting{
+			{"Requests", fmt.Sprintf("%d", s.requestCount())},
+			{"Failed requests", fmt.Sprintf("%d", s.errorCount)},
+			{"Failed checks", fmt.Sprintf("%d", s.checkFailedCount)},
+			{"Dropped requests", fmt.Sprintf("%d", s.droppedCount)},
+			{"Mean RPS", fmt.Sprintf("%.2f", s.avgRPS*float64(time.Second))},
+			{"Total time", formatMs(s.endTime.Sub(s.startTime))},
+			{"Mean latency", formatMs(s.avgDuration)},
+			{"Fastest", formatMs(s.minDuration)},
+			{"Slowest", formatMs(s.maxDuration)},
+			{"50th percentile", formatMs(s.p50Duration)},
+			{"90th percentile", formatMs(s.p90Duration)},
+			{"95th percentile", formatMs(s.p95Duration)},
+			{"99th percentile", formatMs(s.p99Duration)},
+			{"99.9th percentile", formatMs(s.p999Duration)},
+			{"Standard deviation", formatMs(s.stdDevDuration)},
+			{"Total data transferred", fmt.Sprintf("%d bytes", s.totalDataTransferred)},
+		},
+	}
+
+	var codes []int
+	total := 0
+	for code, count := range s.statusCodes {
+		codes = append(codes, code)
+		total += count
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		section.StatusCodes = append(section.StatusCodes, reportStatusCode{
+			Code:    fmt.Sprintf("%d", code),
+			Count:   s.statusCodes[code],
+			Percent: 100 * float64(s.statusCodes[code]) / float64(total),
+		})
+	}
+
+	var binLabels []string
+	var binCounts []float64
+	for _, bin := range s.latencies.bins(histogramBinCount) {
+		binLabels = append(binLabels, fmt.Sprintf("%.1f", float64(bin.from)/float64(time.Millisecond)))
+		binCounts = append(binCounts, float64(bin.count))
+	}
+	section.Histogram = svgBarChart(binLabels, binCounts, "ms", "requests")
+
+	if s.latencies != nil && s.latencies.totalCount > 0 {
+		var percentiles []float64
+		for _, p := range reportPercentiles {
+			percentiles = append(percentiles, durationMs(s.latencies.valueAtPercentile(p)))
+		}
+		section.Percentiles = svgLineChart(reportPercentiles, "percentile", "ms",
+			chartSeries{name: "latency", color: "#4a7fb5", values: percentiles})
+	} else {
+		section.Percentiles = svgLineChart(nil, "percentile", "ms")
+	}
+
+	series := CreateTimeSeries(stats, interval)
+	var offsets, rps, p50, p99 []float64
+	for _, bucket := range series {
+		offsets = append(offsets, bucket.Offset.Seconds())
+		rps = append(rps, bucket.RPS)
+		p50 = append(p50, durationMs(bucket.P50Duration))
+		p99 = append(p99, durationMs(bucket.P99Duration))
+	}
+	section.Throughput = svgLineChart(offsets, "seconds", "req/sec",
+		chartSeries{name: "requests per second", color: "#4a7fb5", values: rps})
+	section.Latency = svgLineChart(offsets, "seconds", "ms",
+		chartSeries{name: "p50", color: "#4a7fb5", values: p50},
+		chartSeries{name: "p99", color: "#c0504d", values: p99})
+	return section
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.2f ms", durationMs(d))
+}
+
+// chartSeries is one line of a line chart
+type chartSeries struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// svgBarChart draws a bar per value, labelled underneath
+func svgBarChart(labels []string, values []float64, xUnit, yUnit string) template.HTML {
+	var b bytes.Buffer
+	svgStart(&b)
+	if len(values) == 0 {
+		svgEmpty(&b)
+		return svgEnd(&b)
+	}
+	maxValue := maxOf(values)
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	barWidth := plotWidth / float64(len(values))
+	for i, v := range values {
+		height := 0.0
+		if maxValue > 0 {
+			height = v / maxValue * plotHeight
+		}
+		x := chartPadding + float64(i)*barWidth
+		y := chartPadding + plotHeight - height
+		fmt.Fprintf(&b, `<rect class="bar" x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s %s: %g %s</title></rect>`,
+			x+1, y, barWidth-2, height, template.HTMLEscapeString(labels[i]), xUnit, v, yUnit)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+			x+barWidth/2, chartHeight-chartPadding+14, template.HTMLEscapeString(labels[i]))
+	}
+	svgAxes(&b, xUnit, yUnit, 0, maxValue)
+	return svgEnd(&b)
+}
+
+// svgLineChart draws each series against the shared xs
+func svgLineChart(xs []float64, xUnit, yUnit string, series ...chartSeries) template.HTML {
+	var b bytes.Buffer
+	svgStart(&b)
+	if len(xs) == 0 || len(series) == 0 {
+		svgEmpty(&b)
+		return svgEnd(&b)
+	}
+	minX, maxX := xs[0], xs[len(xs)-1]
+	var maxY float64
+	for _, s := range series {
+		if m := maxOf(s.values); m > maxY {
+			maxY = m
+		}
+	}
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	for idx, s := range series {
+		fmt.Fprintf(&b, `<polyline class="line" stroke="%s" points="`, s.color)
+		for i, v := range s.values {
+			x := chartPadding + plotWidth/2
+			if maxX > minX {
+				x = chartPadding + (xs[i]-minX)/(maxX-minX)*plotWidth
+			}
+			y := chartPadding + plotHeight
+			if maxY > 0 {
+				y -= v / maxY * plotHeight
+			}
+			fmt.Fprintf(&b, "%.1f,%.1f ", x, y)
+		}
+		b.WriteString(`"/>`)
+		//legend along the top
+		fmt.Fprintf(&b, `<text x="%d" y="%d" style="fill:%s">%s</text>`,
+			chartPadding+idx*150, chartPadding-10, s.color, template.HTMLEscapeString(s.name))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%g</text>`, chartPadding, chartHeight-chartPadding+14, minX)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%g</text>`, chartWidth-chartPadding, chartHeight-chartPadding+14, maxX)
+	svgAxes(&b, xUnit, yUnit, 0, maxY)
+	return svgEnd(&b)
+}
+
+func svgStart(b *bytes.Buffer) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		chartWidth, chartHeight, chartWidth, chartHeight)
+}
+
+func svgEnd(b *bytes.Buffer) template.HTML {
+	b.WriteString("</svg>")
+	//safe, as charts are only built from numbers and escaped labels
+	return template.HTML(b.String())
+}
+
+func svgEmpty(b *bytes.Buffer) {
+	fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="middle">No data</text>`, chartWidth/2, chartHeight/2)
+}
+
+// svgAxes draws the x and y axes with the range of the y axis and the units
+func svgAxes(b *bytes.Buffer, xUnit, yUnit string, minY, maxY float64) {
+	bottom := chartHeight - chartPadding
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartPadding, bottom, chartWidth-chartPadding, bottom)
+	fmt.Fprintf(b, `<line class="axis" x1="%d" y1="%d" x2="%d" y2="%d"/>`, chartPadding, chartPadding, chartPadding, bottom)
+	fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="end">%.4g</text>`, chartPadding-4, chartPadding+4, maxY)
+	fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="end">%.4g</text>`, chartPadding-4, bottom, minY)
+	fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`, chartWidth/2, chartHeight-8, template.HTMLEscapeString(xUnit))
+	fmt.Fprintf(b, `<text x="4" y="%d">%s</text>`, chartPadding-24, template.HTMLEscapeString(yUnit))
+}
+
+func maxOf(values []float64) float64 {
+	var m float64
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,81 @@
+package pewpew
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	start := time.Unix(1000, 0)
+	targetStats := []RequestStat{
+		{StartTime: start, EndTime: start.Add(10 * time.Millisecond), Duration: 10 * time.Millisecond, StatusCode: 200},
+		{StartTime: start.Add(time.Second), EndTime: start.Add(time.Second + 30*time.Millisecond), Duration: 30 * time.Millisecond, StatusCode: 503},
+		{StartTime: start.Add(2 * time.Second), Error: errors.New("connection refused")},
+	}
+	tests := []struct {
+		name         string
+		report       Report
+		wantContains []string
+	}{
+		{
+			name:         "empty",
+			report:       Report{Title: "Empty test"},
+			wantContains: []string{"<h1>Empty test</h1>", "<h2>Summary</h2>", "No responses", "No data"},
+		},
+		{
+			name: "single target",
+			report: Report{
+				Title:              "Stress test",
+				Settings:           []ReportSetting{{Name: "Concurrency", Value: "4"}},
+				Targets:            []Target{{URL: "http://localhost", Options: TargetOptions{Method: "GET"}}},
+				TargetRequestStats: [][]RequestStat{targetStats},
+			},
+			wantContains: []string{
+				"<th>Concurrency</th><td>4</td>",
+				"<h2>Summary</h2>",
+				"<td>503</td>",
+				"<polyline",
+				"<rect",
+			},
+		},
+		{
+			name: "multiple targets are escaped",
+			report: Report{
+				Title: "Benchmark",
+				Targets: []Target{
+					{URL: "http://localhost/<script>", Options: TargetOptions{Method: "GET"}},
+					{URL: "http://localhost/b", Options: TargetOptions{Method: "POST"}},
+				},
+				TargetRequestStats: [][]RequestStat{targetStats, targetStats[:1]},
+				Interval:           500 * time.Millisecond,
+			},
+			wantContains: []string{
+				"Target 1: GET http://localhost/&lt;script&gt;",
+				"Target 2: POST http://localhost/b",
+				"<h2>Global</h2>",
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var b bytes.Buffer
+			if err := WriteHTMLReport(&b, tc.report); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			html := b.String()
+			for _, want := range tc.wantContains {
+				if !strings.Contains(html, want) {
+					t.Errorf("report is missing %q", want)
+				}
+			}
+			if strings.Contains(html, "<script") || strings.Contains(html, "<link") || strings.Contains(html, "src=") {
+				t.Error("report must not have scripts or external assets")
+			}
+		})
+	}
+}
@@ -2,14 +2,75 @@ package pewpew
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/http/httputil"
+	"sync"
 	"time"
 )
 
-func runRequest(req http.Request, client *http.Client) (response *http.Response, stat RequestStat) {
+// requestTrace records when each phase of a request happened.
+// Trace hooks can be called from other goroutines, so access is locked.
+type requestTrace struct {
+	lock          sync.Mutex
+	dnsStart      time.Time
+	dnsDone       time.Time
+	connectStart  time.Time
+	connectDone   time.Time
+	tlsStart      time.Time
+	tlsDone       time.Time
+	wroteRequest  time.Time
+	firstByteTime time.Time
+}
+
+func (rt *requestTrace) clientTrace() *httptrace.ClientTrace {
+	set := func(t *time.Time) {
+		rt.lock.Lock()
+		*t = time.Now()
+		rt.lock.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&rt.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&rt.dnsDone) },
+		ConnectStart:         func(string, string) { set(&rt.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&rt.connectDone) },
+		TLSHandshakeStart:    func() { set(&rt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&rt.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&rt.wroteRequest) },
+		GotFirstResponseByte: func() { set(&rt.firstByteTime) },
+	}
+}
+
+// phaseDuration is the time between start and end, or zero if the phase didn't happen,
+// such as DNS and connecting when a kept alive connection is reused
+func phaseDuration(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// applyTo fills in the phase timings of stat
+func (rt *requestTrace) applyTo(stat *RequestStat) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	stat.DNSDuration = phaseDuration(rt.dnsStart, rt.dnsDone)
+	stat.ConnectDuration = phaseDuration(rt.connectStart, rt.connectDone)
+	stat.TLSDuration = phaseDuration(rt.tlsStart, rt.tlsDone)
+	stat.TimeToFirstByte = phaseDuration(rt.wroteRequest, rt.firstByteTime)
+}
+
+// runRequest sends req and records how it went. The response body is read
+// in full, so it is replaced with a copy that can be read again.
+func runRequest(req http.Request, client *http.Client, checks *responseChecks) (response *http.Response, stat RequestStat) {
+	trace := &requestTrace{}
+	req = *req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
 	reqStartTime := time.Now()
 
 	// get size of request
@@ -35,49 +96,97 @@ func runRequest(req http.Request, client *http.Client) (response *http.Response,
 			StatusCode:      0,
 			Error:           responseErr,
 			DataTransferred: 0,
+
+			IntendedStartTime: reqStartTime,
+			ResponseTime:      reqEndTime.Sub(reqStartTime),
 		}
+		trace.applyTo(&stat)
 		return
 	}
 
 	// get size of response
 	respDump, _ := httputil.DumpResponse(response, false)
 	respBody, _ := ioutil.ReadAll(response.Body)
+	bodyReadTime := time.Now()
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(respBody))
 	totalSizeReceivedBytes := len(respDump) + len(respBody)
 
 	stat = RequestStat{
-		Proto:           response.Proto,
-		URL:             req.URL.String(),
-		Method:          req.Method,
-		StartTime:       reqStartTime,
-		EndTime:         reqEndTime,
-		Duration:        reqEndTime.Sub(reqStartTime),
-		StatusCode:      response.StatusCode,
-		Error:           responseErr,
-		DataTransferred: totalSizeSentBytes + totalSizeReceivedBytes,
+		Proto:            response.Proto,
+		URL:              req.URL.String(),
+		Method:           req.Method,
+		StartTime:        reqStartTime,
+		EndTime:          reqEndTime,
+		Duration:         reqEndTime.Sub(reqStartTime),
+		StatusCode:       response.StatusCode,
+		Error:            responseErr,
+		DataTransferred:  totalSizeSentBytes + totalSizeReceivedBytes,
+		TransferDuration: bodyReadTime.Sub(reqEndTime),
+		CheckFailures:    checks.check(response, respBody),
+
+		IntendedStartTime: reqStartTime,
+		ResponseTime:      reqEndTime.Sub(reqStartTime),
 	}
+	trace.applyTo(&stat)
 	return
 }
 
-// createRequestQueue creates a channel of http.Requests of size count
-func createRequestQueue(count int, target Target) (chan http.Request, error) {
-	requestQueue := make(chan http.Request)
-	//attempt to build one request - if passes, the rest should too
-	_, err := buildRequest(target)
+// abortedByCancel is whether the request behind stat was cut short because ctx was cancelled,
+// rather than completing or failing on its own
+func abortedByCancel(ctx context.Context, stat RequestStat) bool {
+	return ctx.Err() != nil && stat.Error != nil && errors.Is(stat.Error, ctx.Err())
+}
+
+// queuedRequest is a request from a request queue, or the error creating it
+type queuedRequest struct {
+	req http.Request
+	err error
+}
+
+// send sends the request, or if it couldn't be created, is a failed RequestStat of target
+func (q queuedRequest) send(ctx context.Context, target Target, client *http.Client, checks *responseChecks) (*http.Response, RequestStat) {
+	if q.err != nil {
+		now := time.Now()
+		return nil, RequestStat{
+			URL:               target.URL,
+			Method:            target.Options.Method,
+			StartTime:         now,
+			EndTime:           now,
+			Error:             q.err,
+			IntendedStartTime: now,
+		}
+	}
+	return runRequest(*q.req.WithContext(ctx), client, checks)
+}
+
+// createRequestQueue creates a channel of requests of size count.
+// A count of zero or less keeps creating requests until ctx is done,
+// or the Target's RequestFile or DataFile runs out of requests.
+func createRequestQueue(ctx context.Context, count int, target Target) (chan queuedRequest, error) {
+	requestQueue := make(chan queuedRequest)
+	source, err := newRequestSource(target)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request with target configuration: %s", err)
 	}
 	go func() {
-		for i := 0; i < count; i++ {
-			req, err := buildRequest(target)
+		defer close(requestQueue)
+		for i := 0; count <= 0 || i < count; i++ {
+			req, ok, err := source.next()
+			if !ok {
+				return
+			}
+			//a request that can't be created, such as from a template failing
+			//on a row of the DataFile, is still queued so it's counted as failed
 			if err != nil {
-				//this shouldn't happen, but probably should handle for it
-				//usually happens when regex generating an invalid URL
-				i--
-				continue
+				err = fmt.Errorf("failed to create request: %w", err)
+			}
+			select {
+			case requestQueue <- queuedRequest{req: req, err: err}:
+			case <-ctx.Done():
+				return
 			}
-			requestQueue <- req
 		}
-		close(requestQueue)
 	}()
 	return requestQueue, nil
 }
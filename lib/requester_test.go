@@ -1,7 +1,10 @@
 package pewpew
 
 import (
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -36,7 +39,64 @@ func TestRunRequest(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			runRequest(tc.r, tc.c)
+			runRequest(tc.r, tc.c, nil)
 		})
 	}
 }
+
+func TestRunRequestTimingBreakdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	_, stat := runRequest(*req, &http.Client{}, nil)
+	if stat.Error != nil {
+		t.Fatalf("got error: %s", stat.Error)
+	}
+	if stat.ConnectDuration <= 0 {
+		t.Errorf("got connect duration %s, wanted greater than zero", stat.ConnectDuration)
+	}
+	if stat.TimeToFirstByte <= 0 {
+		t.Errorf("got time to first byte %s, wanted greater than zero", stat.TimeToFirstByte)
+	}
+	if stat.TLSDuration != 0 {
+		t.Errorf("got TLS duration %s for plain HTTP, wanted zero", stat.TLSDuration)
+	}
+	if stat.DNSDuration+stat.ConnectDuration+stat.TLSDuration+stat.TimeToFirstByte > stat.Duration {
+		t.Errorf("got phases longer than the total duration %s", stat.Duration)
+	}
+}
+
+func TestRunRequestChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status": "ok"}`)
+	}))
+	defer server.Close()
+
+	checks, err := newResponseChecks(TargetOptions{ExpectStatus: "2xx", ExpectJSON: []string{"status=ok", "id"}})
+	if err != nil {
+		t.Fatalf("failed to create checks: %s", err)
+	}
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err)
+	}
+	response, stat := runRequest(*req, &http.Client{}, checks)
+	if stat.Error != nil {
+		t.Fatalf("got error: %s", stat.Error)
+	}
+	if len(stat.CheckFailures) != 1 || stat.CheckFailures[0] != "JSON path id not found" {
+		t.Errorf("got check failures %q, wanted only the missing id", stat.CheckFailures)
+	}
+	//the body was read for the checks, but should still be readable
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil || string(body) != `{"status": "ok"}` {
+		t.Errorf("got body %q and error %v after the request", body, err)
+	}
+}
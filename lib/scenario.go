@@ -0,0 +1,395 @@
+package pewpew
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Step is one request of a scenario. Its Target's URL, Body, Headers, Cookies,
+// BasicAuth and RawHeaders are always templates, with the variables extracted
+// by earlier Steps and the row of the scenario's DataFile as their data,
+// so a variable is used like {{.token}}.
+type Step struct {
+	//Name describes the Step in the output. Defaults to its method and URL.
+	Name string
+	//Extract sets variables from the response for the Steps after this one,
+	//each as NAME=SOURCE:EXPRESSION, where SOURCE is one of ExtractJSON,
+	//ExtractRegex, ExtractHeader, or ExtractCookie,
+	//eg. "token=json:data.token" or "session=cookie:SESSIONID"
+	Extract []string
+
+	//squashed so that target settings are set directly on a step in a config file
+	Target Target `mapstructure:",squash"`
+}
+
+// ScenarioConfig is the configuration for a scenario test, where each virtual
+// user runs through the Steps in order, over and over. Each run through the
+// Steps is an iteration, which fails at the first Step that fails. A Step fails
+// if its request fails, its response fails any of the Target's response checks,
+// or any of its extractions isn't found. Without an ExpectStatus, a 4xx or 5xx
+// response also fails the Step.
+type ScenarioConfig struct {
+	Verbose bool
+	Quiet   bool
+
+	//Iterations is how many total times to run through the Steps, across all users.
+	//Zero means no limit, as long as Duration is set.
+	Iterations int
+	//Duration is the number of seconds to keep starting iterations.
+	//Zero means no limit, as long as Iterations is set. When both are set,
+	//the test stops at whichever is reached first.
+	Duration int
+	//Users is how many virtual users run through the Steps simultaneously
+	Users int
+	Steps []Step
+	//DataFile is a CSV or JSON file of rows of data, like a Target's DataFile.
+	//Each iteration starts with the next row as its variables.
+	DataFile string
+	//DataOrder is the order the DataFile's rows are used in, one of
+	//DataOrderSequential, DataOrderRandom, or DataOrderUnique
+	DataOrder string
+	//MinSuccessRate is the lowest acceptable percentage of iterations that
+	//complete every Step. Zero means any rate is acceptable.
+	MinSuccessRate float64
+	//Thresholds are pass/fail conditions checked against the summary of all Steps combined
+	Thresholds []string
+	//Observers are notified about each request as the test runs,
+	//with the index of the Step as the target
+	Observers []Observer
+}
+
+// ScenarioIteration is the outcome of one run through a scenario's Steps
+type ScenarioIteration struct {
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	//FailedStep is the index of the Step the iteration stopped at, or -1 if every Step succeeded
+	FailedStep int `json:"failedStep"`
+	//Failure describes why the Step failed
+	Failure string `json:"failure"`
+}
+
+// scenarioRun is an iteration waiting for a virtual user to run it
+type scenarioRun struct {
+	//number of the iteration, from 1
+	seq  int64
+	vars map[string]string
+}
+
+// RunScenario starts the scenario test with the provided ScenarioConfig.
+// It returns the RequestStats of each Step and the outcome of each iteration.
+// Throughout the test, data is sent to w, useful for live updates.
+func RunScenario(s ScenarioConfig, w io.Writer) ([][]RequestStat, []ScenarioIteration, error) {
+	return RunScenarioContext(context.Background(), s, w)
+}
+
+// RunScenarioContext is RunScenario, but stops early when ctx is done.
+// Iterations in flight are aborted and left out of the results, along with
+// any of their requests, and ctx's error is returned.
+func RunScenarioContext(ctx context.Context, s ScenarioConfig, w io.Writer) ([][]RequestStat, []ScenarioIteration, error) {
+	if w == nil {
+		return nil, nil, errors.New("nil writer")
+	}
+	err := validateScenarioConfig(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	var feeder *dataFeeder
+	if s.DataFile != "" {
+		feeder, err = newDataFeeder(s.DataFile, s.DataOrder)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	p := printer{output: w}
+	switch {
+	case s.Duration <= 0:
+		p.writeString(fmt.Sprintf("Running %d iterations of %d steps, %d users at a time\n", s.Iterations, len(s.Steps), s.Users))
+	case s.Iterations <= 0:
+		p.writeString(fmt.Sprintf("Running iterations of %d steps for %d seconds, %d users at a time\n", len(s.Steps), s.Duration, s.Users))
+	default:
+		p.writeString(fmt.Sprintf("Running up to %d iterations of %d steps for up to %d seconds, %d users at a time\n", s.Iterations, len(s.Steps), s.Duration, s.Users))
+	}
+
+	//every user shares the clients, checks and extractors of each step
+	r := &scenarioRunner{config: s, printer: &p}
+	for _, step := range s.Steps {
+		r.clients = append(r.clients, createClient(step.Target))
+		//already validated
+		checks, _ := newResponseChecks(step.Target.Options)
+		r.checks = append(r.checks, checks)
+		var extractors []extractor
+		for _, spec := range step.Extract {
+			e, _ := newExtractor(spec)
+			extractors = append(extractors, e)
+		}
+		r.extractors = append(r.extractors, extractors)
+	}
+
+	//when the duration is up, no more iterations start but those in flight are left to finish
+	queueCtx, stopQueue := context.WithCancel(ctx)
+	if s.Duration > 0 {
+		queueCtx, stopQueue = context.WithTimeout(ctx, time.Duration(s.Duration)*time.Second)
+	}
+	defer stopQueue()
+	runQueue := make(chan scenarioRun)
+	go func() {
+		defer close(runQueue)
+		for i := 1; s.Iterations <= 0 || i <= s.Iterations; i++ {
+			run := scenarioRun{seq: int64(i), vars: make(map[string]string)}
+			if feeder != nil {
+				row, ok := feeder.next()
+				if !ok {
+					return
+				}
+				for key, val := range row {
+					run.vars[key] = val
+				}
+			}
+			select {
+			case runQueue <- run:
+			case <-queueCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var lock sync.Mutex
+	stepStats := make([][]RequestStat, len(s.Steps))
+	var iterations []ScenarioIteration
+	var wg sync.WaitGroup
+	for u := 0; u < s.Users; u++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			//templates aren't safe for concurrent use, so each user has its own
+			tmpl, _ := newRequestTemplate(Target{})
+			for run := range runQueue {
+				tmpl.seq = run.seq
+				iteration := ScenarioIteration{StartTime: time.Now(), FailedStep: -1}
+				//the RequestStat of each step that sent its request, in order
+				var stats []RequestStat
+				aborted := false
+				for i := range s.Steps {
+					stat, failure, ok := r.runStep(ctx, i, tmpl, run.vars)
+					if !ok {
+						aborted = true
+						break
+					}
+					if stat != nil {
+						stats = append(stats, *stat)
+					}
+					if failure != "" {
+						iteration.FailedStep = i
+						iteration.Failure = failure
+						break
+					}
+				}
+				if aborted {
+					continue
+				}
+				iteration.EndTime = time.Now()
+				lock.Lock()
+				for i, stat := range stats {
+					stepStats[i] = append(stepStats[i], stat)
+				}
+				iterations = append(iterations, iteration)
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(iterations, func(i, j int) bool {
+		return iterations[i].StartTime.Before(iterations[j].StartTime)
+	})
+	return stepStats, iterations, ctx.Err()
+}
+
+// scenarioRunner runs the Steps of a ScenarioConfig
+type scenarioRunner struct {
+	config  ScenarioConfig
+	printer *printer
+	//clients, checks and extractors of each Step
+	clients    []*http.Client
+	checks     []*responseChecks
+	extractors [][]extractor
+}
+
+// runStep sends the request of the Step at index idx with the iteration's
+// variables, then extracts the Step's variables from the response into vars.
+// It returns the RequestStat of the request, if it was sent, and why the Step
+// failed, if it did. It returns false if the request was cut short because
+// ctx was cancelled.
+func (r *scenarioRunner) runStep(ctx context.Context, idx int, tmpl *requestTemplate, vars map[string]string) (*RequestStat, string, bool) {
+	step := r.config.Steps[idx]
+	t, err := tmpl.renderRow(step.Target, vars)
+	if err != nil {
+		return nil, err.Error(), true
+	}
+	req, err := buildRequest(t)
+	if err != nil {
+		return nil, "failed to build request: " + err.Error(), true
+	}
+	notifyStarted(r.config.Observers, idx)
+	response, stat := runRequest(*req.WithContext(ctx), r.clients[idx], r.checks[idx])
+	notifyFinished(r.config.Observers, idx, stat)
+	if abortedByCancel(ctx, stat) {
+		return nil, "", false
+	}
+
+	var failure string
+	var body []byte
+	if response != nil {
+		//runRequest already read the body, so this is a copy that can be read again
+		body, _ = ioutil.ReadAll(response.Body)
+		response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	switch {
+	case stat.Error != nil:
+		failure = "request failed: " + stat.Error.Error()
+	case len(stat.CheckFailures) > 0:
+		failure = stat.CheckFailures[0]
+	case step.Target.Options.ExpectStatus == "" && stat.StatusCode >= 400:
+		failure = fmt.Sprintf("status %d", stat.StatusCode)
+	default:
+		if err := extractVariables(r.extractors[idx], response, body, vars); err != nil {
+			failure = err.Error()
+		}
+	}
+
+	if !r.config.Quiet {
+		r.printer.printStat(stat)
+		if r.config.Verbose {
+			r.printer.printVerbose(&req, response)
+		}
+	}
+	return &stat, failure, true
+}
+
+// StepName is how the Step at index idx is described in the output
+func StepName(idx int, step Step) string {
+	if step.Name != "" {
+		return fmt.Sprintf("Step %d: %s", idx+1, step.Name)
+	}
+	return fmt.Sprintf("Step %d: %s %s", idx+1, step.Target.Options.Method, step.Target.URL)
+}
+
+// ScenarioSuccessRate is the percentage of iterations that completed every Step
+func ScenarioSuccessRate(iterations []ScenarioIteration) float64 {
+	if len(iterations) == 0 {
+		return 0
+	}
+	succeeded := 0
+	for _, iteration := range iterations {
+		if iteration.FailedStep < 0 {
+			succeeded++
+		}
+	}
+	return 100 * float64(succeeded) / float64(len(iterations))
+}
+
+// CreateScenarioSummary creates a human friendly summary of the iterations
+// of a scenario, and why they failed
+func CreateScenarioSummary(steps []Step, iterations []ScenarioIteration) string {
+	summary := "\n"
+	if len(iterations) == 0 {
+		return summary + "No iterations completed\n"
+	}
+	succeeded := 0
+	var totalDuration time.Duration
+	failures := make([]map[string]int, len(steps))
+	for _, iteration := range iterations {
+		totalDuration += iteration.EndTime.Sub(iteration.StartTime)
+		if iteration.FailedStep < 0 {
+			succeeded++
+			continue
+		}
+		if failures[iteration.FailedStep] == nil {
+			failures[iteration.FailedStep] = make(map[string]int)
+		}
+		failures[iteration.FailedStep][iteration.Failure]++
+	}
+	summary += fmt.Sprintf("Iterations:       %d\n", len(iterations))
+	summary += fmt.Sprintf("Succeeded:        %d\n", succeeded)
+	summary += fmt.Sprintf("Failed:           %d\n", len(iterations)-succeeded)
+	summary += fmt.Sprintf("Success rate:     %.2f%%\n", ScenarioSuccessRate(iterations))
+	summary += fmt.Sprintf("Mean iteration:   %d ms\n", totalDuration/time.Duration(len(iterations))/time.Millisecond)
+
+	if succeeded == len(iterations) {
+		return summary
+	}
+	summary += "\nFailures\n"
+	for i, stepFailures := range failures {
+		if len(stepFailures) == 0 {
+			continue
+		}
+		count := 0
+		//sort the failures so the output is stable
+		var reasons []string
+		for reason, n := range stepFailures {
+			reasons = append(reasons, reason)
+			count += n
+		}
+		sort.Strings(reasons)
+		summary += fmt.Sprintf("%s: %d\n", StepName(i, steps[i]), count)
+		for _, reason := range reasons {
+			summary += fmt.Sprintf("  %s: %d\n", reason, stepFailures[reason])
+		}
+	}
+	return summary
+}
+
+func validateScenarioConfig(s ScenarioConfig) error {
+	if len(s.Steps) == 0 {
+		return errors.New("zero steps")
+	}
+	if s.Iterations < 0 {
+		return errors.New("iteration count cannot be negative")
+	}
+	if s.Duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+	if s.Iterations == 0 && s.Duration == 0 {
+		return errors.New("iteration count or duration must be greater than zero")
+	}
+	if s.Users <= 0 {
+		return errors.New("users must be greater than zero")
+	}
+	if s.MinSuccessRate < 0 || s.MinSuccessRate > 100 {
+		return errors.New("minimum success rate must be between 0 and 100")
+	}
+	if err := validateDataOrder(s.DataOrder); err != nil {
+		return err
+	}
+	if err := validateThresholds(s.Thresholds); err != nil {
+		return err
+	}
+
+	tmpl, _ := newRequestTemplate(Target{})
+	for i, step := range s.Steps {
+		if step.Target.RequestFile != "" || step.Target.DataFile != "" {
+			return fmt.Errorf("step %d: steps cannot have a request file or data file", i+1)
+		}
+		if err := validateTarget(step.Target); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if err := tmpl.parseTarget(step.Target); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		for _, spec := range step.Extract {
+			if _, err := newExtractor(spec); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
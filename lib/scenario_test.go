@@ -0,0 +1,253 @@
+package pewpew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newScenarioServer serves a login, create, then fetch by id flow.
+// Creating an item fails for the user "bad".
+func newScenarioServer() *httptest.Server {
+	var nextID int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-" + string(body)})
+		fmt.Fprintf(w, `{"token": "t-%s"}`, body)
+	})
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie("session")
+		if err != nil || r.Header.Get("Authorization") != "Bearer t-"+strings.TrimPrefix(session.Value, "s-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if session.Value == "s-bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		id := atomic.AddInt64(&nextID, 1)
+		w.Header().Set("Location", fmt.Sprintf("/items/%d", id))
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/items/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<item id="%s">`, strings.TrimPrefix(r.URL.Path, "/items/"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func scenarioSteps(url string) []Step {
+	return []Step{
+		{
+			Name:    "login",
+			Extract: []string{"token=json:token", "session=cookie:session"},
+			Target:  Target{URL: url + "/login", Options: TargetOptions{Method: "POST", Body: "{{.user}}"}},
+		},
+		{
+			Name:    "create",
+			Extract: []string{"location=header:Location"},
+			Target: Target{URL: url + "/items", Options: TargetOptions{
+				Method:     "POST",
+				Cookies:    "session={{.session}}",
+				RawHeaders: map[string]string{"Authorization": "Bearer {{.token}}"},
+			}},
+		},
+		{
+			Extract: []string{`id=regex:id="(\d+)"`},
+			Target:  Target{URL: url + "{{.location}}", Options: TargetOptions{Method: "GET"}},
+		},
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	server := newScenarioServer()
+	defer server.Close()
+
+	s := ScenarioConfig{
+		Iterations: 6,
+		Users:      2,
+		Quiet:      true,
+		Steps:      scenarioSteps(server.URL),
+		DataFile:   writeTempFile(t, "users.csv", "user\nann\nbad\nbob\n"),
+	}
+	stats, iterations, err := RunScenario(s, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(iterations) != 6 {
+		t.Fatalf("got %d iterations, wanted 6", len(iterations))
+	}
+	//every iteration of the user "bad" stops at the create step
+	if len(stats[0]) != 6 || len(stats[1]) != 6 || len(stats[2]) != 4 {
+		t.Errorf("got %d, %d, %d requests for each step, wanted 6, 6, 4", len(stats[0]), len(stats[1]), len(stats[2]))
+	}
+	failed := 0
+	for _, iteration := range iterations {
+		if iteration.FailedStep < 0 {
+			continue
+		}
+		failed++
+		if iteration.FailedStep != 1 || iteration.Failure != "status 500" {
+			t.Errorf("got iteration failed at step %d: %s", iteration.FailedStep, iteration.Failure)
+		}
+	}
+	if failed != 2 {
+		t.Errorf("got %d failed iterations, wanted 2", failed)
+	}
+	if rate := ScenarioSuccessRate(iterations); rate < 66.6 || rate > 66.7 {
+		t.Errorf("got success rate %.2f, wanted 66.67", rate)
+	}
+	summary := CreateScenarioSummary(s.Steps, iterations)
+	if !strings.Contains(summary, "Success rate:     66.67%") || !strings.Contains(summary, "Step 2: create: 2\n  status 500: 2") {
+		t.Errorf("got summary %s", summary)
+	}
+}
+
+func TestRunScenarioFailures(t *testing.T) {
+	server := newScenarioServer()
+	//the subtests are parallel, so are still running when this returns
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name        string
+		steps       func([]Step) []Step
+		wantStep    int
+		wantFailure string //start of the failure
+	}{
+		{
+			name: "extraction not found",
+			steps: func(steps []Step) []Step {
+				steps[0].Extract = []string{"token=json:data.token"}
+				return steps
+			},
+			wantStep:    0,
+			wantFailure: "failed to extract token: JSON path data.token not found",
+		},
+		{
+			name: "variable never extracted",
+			steps: func(steps []Step) []Step {
+				steps[0].Extract = nil
+				return steps
+			},
+			wantStep:    1,
+			wantFailure: "cookies template: ",
+		},
+		{
+			name: "expected status allows an error status",
+			steps: func(steps []Step) []Step {
+				steps[1].Target.Options.Cookies = ""
+				steps[1].Target.Options.ExpectStatus = "401"
+				steps[1].Extract = nil
+				return steps[:2]
+			},
+			wantStep: -1,
+		},
+		{
+			name: "failed check",
+			steps: func(steps []Step) []Step {
+				steps[2].Target.Options.ExpectBodyRegex = "^item"
+				return steps
+			},
+			wantStep:    2,
+			wantFailure: "body does not match ^item",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			steps := scenarioSteps(server.URL)
+			steps[0].Target.Options.Body = "ann"
+			s := ScenarioConfig{Iterations: 1, Users: 1, Quiet: true, Steps: tc.steps(steps)}
+			_, iterations, err := RunScenario(s, ioutil.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(iterations) != 1 {
+				t.Fatalf("got %d iterations, wanted 1", len(iterations))
+			}
+			if iterations[0].FailedStep != tc.wantStep || !strings.HasPrefix(iterations[0].Failure, tc.wantFailure) ||
+				(tc.wantFailure == "") != (iterations[0].Failure == "") {
+				t.Errorf("got failure at step %d: %q, wanted step %d: %q", iterations[0].FailedStep, iterations[0].Failure, tc.wantStep, tc.wantFailure)
+			}
+		})
+	}
+}
+
+func TestRunScenarioContext(t *testing.T) {
+	//server is slow enough that the scenario can't finish before being cancelled
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	s := ScenarioConfig{
+		Iterations: 1000,
+		Users:      2,
+		Quiet:      true,
+		Steps: []Step{
+			{Target: Target{URL: server.URL + "/a", Options: TargetOptions{Method: "GET"}}},
+			{Target: Target{URL: server.URL + "/b", Options: TargetOptions{Method: "GET"}}},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	stats, iterations, err := RunScenarioContext(ctx, s, ioutil.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, wanted: %v", err, context.DeadlineExceeded)
+	}
+	if len(iterations) == 0 || len(iterations) >= s.Iterations {
+		t.Errorf("got %d partial iterations, wanted some but fewer than %d", len(iterations), s.Iterations)
+	}
+	//aborted iterations are left out, along with their requests
+	if len(stats[0]) != len(iterations) || len(stats[1]) != len(iterations) {
+		t.Errorf("got %d and %d requests for %d iterations", len(stats[0]), len(stats[1]), len(iterations))
+	}
+}
+
+func TestValidateScenarioConfig(t *testing.T) {
+	step := Step{Target: Target{URL: "http://localhost/{{.id}}", Options: TargetOptions{Method: "GET"}}}
+	tests := []struct {
+		name      string
+		modify    func(*ScenarioConfig)
+		expectErr bool
+	}{
+		{name: "valid", modify: func(s *ScenarioConfig) {}},
+		{name: "duration only", modify: func(s *ScenarioConfig) { s.Iterations = 0; s.Duration = 5 }},
+		{name: "no steps", modify: func(s *ScenarioConfig) { s.Steps = nil }, expectErr: true},
+		{name: "no iterations or duration", modify: func(s *ScenarioConfig) { s.Iterations = 0 }, expectErr: true},
+		{name: "negative iterations", modify: func(s *ScenarioConfig) { s.Iterations = -1 }, expectErr: true},
+		{name: "negative duration", modify: func(s *ScenarioConfig) { s.Duration = -1 }, expectErr: true},
+		{name: "no users", modify: func(s *ScenarioConfig) { s.Users = 0 }, expectErr: true},
+		{name: "success rate over 100", modify: func(s *ScenarioConfig) { s.MinSuccessRate = 101 }, expectErr: true},
+		{name: "unknown data order", modify: func(s *ScenarioConfig) { s.DataOrder = "shuffled" }, expectErr: true},
+		{name: "invalid threshold", modify: func(s *ScenarioConfig) { s.Thresholds = []string{"p95 <"} }, expectErr: true},
+		{name: "invalid step", modify: func(s *ScenarioConfig) { s.Steps[0].Target.Options.Method = "" }, expectErr: true},
+		{name: "invalid template", modify: func(s *ScenarioConfig) { s.Steps[0].Target.URL = "http://localhost/{{" }, expectErr: true},
+		{name: "escaped braces", modify: func(s *ScenarioConfig) { s.Steps[0].Target.Options.Body = `{"a": "{{"{{"}}"}` }},
+		{name: "invalid extraction", modify: func(s *ScenarioConfig) { s.Steps[0].Extract = []string{"id"} }, expectErr: true},
+		{name: "step with data file", modify: func(s *ScenarioConfig) { s.Steps[0].Target.DataFile = "users.csv" }, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s := ScenarioConfig{Iterations: 1, Users: 1, Steps: []Step{step}}
+			tc.modify(&s)
+			err := validateScenarioConfig(s)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+		})
+	}
+}
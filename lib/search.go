@@ -0,0 +1,301 @@
+package pewpew
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Search modes control how the rate of each capacity search probe is picked
+const (
+	//SearchBinary halves the range of possible rates after each probe
+	SearchBinary = "binary"
+	//SearchStep increases the rate by Step after each passing probe
+	SearchStep = "step"
+)
+
+type (
+	//SearchConfig is the top level struct that contains the configuration for a capacity search.
+	//A search runs a series of benchmark probes at different rates to find the highest
+	//rate where latency and errors stay within the criteria.
+	SearchConfig struct {
+		Verbose bool
+		Quiet   bool
+
+		//Mode is how the rate of each probe is picked, SearchBinary or SearchStep
+		Mode string
+		//MinRPS is the lowest rate probed
+		MinRPS int
+		//MaxRPS is the highest rate probed
+		MaxRPS int
+		//Step is the rate increase between probes in SearchStep mode,
+		//and how close the search gets before stopping in SearchBinary mode
+		Step int
+		//Duration is the number of seconds each probe runs
+		Duration int
+		//Arrival is how requests are spaced out within each second of a probe
+		Arrival string
+
+		//Percentile is which latency percentile, from 0 to 100, must stay under MaxLatency
+		Percentile float64
+		//MaxLatency is the highest acceptable latency at Percentile, such as "300ms"
+		MaxLatency string
+		//MaxErrorRate is the highest acceptable percentage of requests that fail or get a 5xx response
+		MaxErrorRate float64
+
+		Targets []Target
+
+		//global target settings
+		Options TargetOptions
+	}
+
+	//SearchProbe is the outcome of benchmarking at a single rate
+	SearchProbe struct {
+		RPS int
+		//Stats are the RequestStats of each Target
+		Stats [][]RequestStat
+		//Passed is whether or not the probe met the criteria
+		Passed bool
+		//Reason is why the probe didn't pass, empty if it did
+		Reason string
+	}
+
+	//SearchResult is the outcome of a capacity search
+	SearchResult struct {
+		//MaxRPS is the highest rate that passed, or zero if none did
+		MaxRPS int
+		//Probes are in the order they were run
+		Probes []SearchProbe
+	}
+)
+
+// searchMinAchievedRate is the lowest fraction of a probe's rate that the
+// client must achieve for the probe to pass. Below it, the client couldn't
+// send requests as fast as asked, so the probe says nothing about that rate.
+const searchMinAchievedRate = 0.9
+
+// NewSearchConfig creates a new SearchConfig
+// with package defaults
+func NewSearchConfig() (s *SearchConfig) {
+	s = &SearchConfig{
+		Mode:         DefaultSearchMode,
+		MinRPS:       DefaultMinRPS,
+		MaxRPS:       DefaultMaxRPS,
+		Step:         DefaultStep,
+		Duration:     DefaultProbeDuration,
+		Arrival:      DefaultArrival,
+		Percentile:   DefaultPercentile,
+		MaxLatency:   DefaultMaxLatency,
+		MaxErrorRate: DefaultMaxErrorRate,
+		Targets: []Target{
+			{
+				URL: DefaultURL,
+				Options: TargetOptions{
+					Timeout:         DefaultTimeout,
+					Method:          DefaultMethod,
+					UserAgent:       DefaultUserAgent,
+					FollowRedirects: true,
+				},
+			},
+		},
+	}
+	return
+}
+
+// RunSearch searches for the highest rate that meets the SearchConfig's criteria,
+// running a benchmark probe at each rate tried.
+// Throughout the search, data is sent to w, useful for live updates.
+func RunSearch(s SearchConfig, w io.Writer) (SearchResult, error) {
+	if w == nil {
+		return SearchResult{}, errors.New("nil writer")
+	}
+	err := validateSearchConfig(s)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	result := SearchResult{}
+	probe := func(rps int) (bool, error) {
+		fmt.Fprintf(w, "\nProbing %d RPS\n", rps)
+		targetStats, err := RunBenchmark(BenchmarkConfig{
+			Verbose:  s.Verbose,
+			Quiet:    s.Quiet,
+			RPS:      rps,
+			Duration: s.Duration,
+			Arrival:  s.Arrival,
+			Targets:  s.Targets,
+			Options:  s.Options,
+		}, w)
+		if err != nil {
+			return false, err
+		}
+		passed, reason := checkSearchCriteria(s, rps, targetStats)
+		result.Probes = append(result.Probes, SearchProbe{
+			RPS:    rps,
+			Stats:  targetStats,
+			Passed: passed,
+			Reason: reason,
+		})
+		if passed {
+			fmt.Fprintf(w, "%d RPS passed\n", rps)
+			if rps > result.MaxRPS {
+				result.MaxRPS = rps
+			}
+		} else {
+			fmt.Fprintf(w, "%d RPS failed: %s\n", rps, reason)
+		}
+		return passed, nil
+	}
+
+	if s.Mode == SearchStep {
+		for rps := s.MinRPS; rps <= s.MaxRPS; rps += s.Step {
+			passed, err := probe(rps)
+			if err != nil {
+				return result, err
+			}
+			if !passed {
+				break
+			}
+		}
+		return result, nil
+	}
+
+	//binary search, where low always passes and high always fails
+	low, high := s.MinRPS, s.MaxRPS
+	passed, err := probe(low)
+	if err != nil || !passed {
+		return result, err
+	}
+	passed, err = probe(high)
+	if err != nil || passed {
+		return result, err
+	}
+	for high-low > s.Step {
+		mid := low + (high-low)/2
+		passed, err := probe(mid)
+		if err != nil {
+			return result, err
+		}
+		if passed {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return result, nil
+}
+
+// checkSearchCriteria decides whether a probe at rps passed, and if not, why
+func checkSearchCriteria(s SearchConfig, rps int, targetStats [][]RequestStat) (bool, string) {
+	summary := probeSummary(targetStats)
+	if summary.requestCount() == 0 {
+		return false, "no requests completed"
+	}
+	maxLatency, _ := time.ParseDuration(s.MaxLatency) //already validated
+	//response time includes any delay sending requests, which service time
+	//would hide once the client falls behind schedule
+	latency := summary.responseTimes.valueAtPercentile(s.Percentile)
+	if latency > maxLatency {
+		return false, fmt.Sprintf("%gth percentile response time %s exceeds %s", s.Percentile, latency.Round(time.Millisecond), maxLatency)
+	}
+	errorRate := summary.errorRate() * 100
+	if errorRate > s.MaxErrorRate {
+		return false, fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", errorRate, s.MaxErrorRate)
+	}
+	achieved := achievedRPS(summary, len(targetStats), time.Duration(s.Duration)*time.Second)
+	if achieved < float64(rps)*searchMinAchievedRate {
+		return false, fmt.Sprintf("achieved %.2f RPS, well below %d RPS", achieved, rps)
+	}
+	return true, ""
+}
+
+// CreateSearchSummary creates a human friendly summary of each probe of a search and its outcome
+func CreateSearchSummary(s SearchConfig, r SearchResult) string {
+	summary := "\n"
+	percentile := fmt.Sprintf("p%g", s.Percentile)
+	summary += fmt.Sprintf("%8s  %12s  %16s  %16s  %10s  %s\n",
+		"RPS", "Achieved RPS", percentile+" response", percentile+" service", "Errors", "Result")
+	for _, probe := range r.Probes {
+		reqStats := probeSummary(probe.Stats)
+		result := "pass"
+		if !probe.Passed {
+			result = "fail: " + probe.Reason
+		}
+		summary += fmt.Sprintf("%8d  %12.2f  %13d ms  %13d ms  %9.2f%%  %s\n",
+			probe.RPS,
+			achievedRPS(reqStats, len(probe.Stats), time.Duration(s.Duration)*time.Second),
+			reqStats.responseTimes.valueAtPercentile(s.Percentile)/time.Millisecond,
+			reqStats.latencies.valueAtPercentile(s.Percentile)/time.Millisecond,
+			reqStats.errorRate()*100,
+			result)
+	}
+	if r.MaxRPS == 0 {
+		summary += "\nNo rate met the criteria\n"
+	} else {
+		summary += fmt.Sprintf("\nMaximum sustainable rate: %d RPS\n", r.MaxRPS)
+	}
+	return summary
+}
+
+// probeSummary summarizes the requests of every target of a probe together
+func probeSummary(targetStats [][]RequestStat) RequestStatSummary {
+	var allStats []RequestStat
+	for _, stats := range targetStats {
+		allStats = append(allStats, stats...)
+	}
+	return CreateRequestsStats(allStats)
+}
+
+// achievedRPS is the rate each of targetCount targets actually got requests at
+// during a probe of duration. Requests that finish after the probe, such as
+// when the client falls behind, stretch out the time they took.
+func achievedRPS(summary RequestStatSummary, targetCount int, duration time.Duration) float64 {
+	if targetCount == 0 {
+		return 0
+	}
+	if took := summary.endTime.Sub(summary.startTime); took > duration {
+		duration = took
+	}
+	return float64(summary.requestCount()) / duration.Seconds() / float64(targetCount)
+}
+
+func validateSearchConfig(s SearchConfig) error {
+	if len(s.Targets) == 0 {
+		return errors.New("zero targets")
+	}
+	if s.Mode != SearchBinary && s.Mode != SearchStep {
+		return fmt.Errorf("unknown search mode %q, must be %s or %s", s.Mode, SearchBinary, SearchStep)
+	}
+	if s.MinRPS <= 0 {
+		return errors.New("minimum RPS must be greater than zero")
+	}
+	if s.MaxRPS < s.MinRPS {
+		return errors.New("maximum RPS must be at least the minimum RPS")
+	}
+	if s.Step <= 0 {
+		return errors.New("step must be greater than zero")
+	}
+	if s.Duration <= 0 {
+		return errors.New("duration must be greater than zero")
+	}
+	if s.Percentile < 0 || s.Percentile > 100 {
+		return errors.New("percentile must be between 0 and 100")
+	}
+	maxLatency, err := time.ParseDuration(s.MaxLatency)
+	if err != nil {
+		return errors.New("failed to parse max latency: " + s.MaxLatency)
+	}
+	if maxLatency <= 0 {
+		return errors.New("max latency must be greater than zero")
+	}
+	if s.MaxErrorRate < 0 {
+		return errors.New("max error rate cannot be negative")
+	}
+	return validateBenchmarkConfig(BenchmarkConfig{
+		RPS:      s.MinRPS,
+		Duration: s.Duration,
+		Arrival:  s.Arrival,
+		Targets:  s.Targets,
+	})
+}
@@ -0,0 +1,220 @@
+package pewpew
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSearch(t *testing.T) {
+	okServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer okServer.Close()
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer errServer.Close()
+
+	searchConfig := func(mode string, url string) SearchConfig {
+		s := *NewSearchConfig()
+		s.Quiet = true
+		s.Mode = mode
+		s.MinRPS = 1
+		s.MaxRPS = 3
+		s.Step = 1
+		s.Duration = 1
+		s.Targets[0].URL = url
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		config     SearchConfig
+		wantMaxRPS int
+		wantProbes int
+		expectErr  bool
+	}{
+		{
+			name:      "empty config",
+			config:    SearchConfig{},
+			expectErr: true,
+		},
+		{
+			name:       "binary, max rate passes",
+			config:     searchConfig(SearchBinary, okServer.URL),
+			wantMaxRPS: 3,
+			wantProbes: 2,
+		},
+		{
+			name:       "binary, min rate fails",
+			config:     searchConfig(SearchBinary, errServer.URL),
+			wantMaxRPS: 0,
+			wantProbes: 1,
+		},
+		{
+			name:       "step, every rate passes",
+			config:     searchConfig(SearchStep, okServer.URL),
+			wantMaxRPS: 3,
+			wantProbes: 3,
+		},
+		{
+			name:       "step, first rate fails",
+			config:     searchConfig(SearchStep, errServer.URL),
+			wantMaxRPS: 0,
+			wantProbes: 1,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := RunSearch(tc.config, ioutil.Discard)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("got error: %t, wanted: %t", (err != nil), tc.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.MaxRPS != tc.wantMaxRPS {
+				t.Errorf("got max RPS: %d, wanted: %d", result.MaxRPS, tc.wantMaxRPS)
+			}
+			if len(result.Probes) != tc.wantProbes {
+				t.Errorf("got %d probes, wanted: %d", len(result.Probes), tc.wantProbes)
+			}
+			_ = CreateSearchSummary(tc.config, result)
+		})
+	}
+}
+
+func TestCheckSearchCriteria(t *testing.T) {
+	s := SearchConfig{Percentile: 99, MaxLatency: "100ms", MaxErrorRate: 10}
+	start := time.Now()
+	//requests to one target spread over a second, each sent delay late and
+	//taking duration to respond once sent
+	overASecond := func(count int, duration, delay time.Duration) []RequestStat {
+		stats := make([]RequestStat, count)
+		for i := range stats {
+			sent := start.Add(time.Duration(i) * time.Second / time.Duration(count-1))
+			stats[i] = RequestStat{
+				StartTime:         sent,
+				EndTime:           sent.Add(duration),
+				Duration:          duration,
+				IntendedStartTime: sent.Add(-delay),
+				ResponseTime:      duration + delay,
+				StatusCode:        200,
+			}
+		}
+		return stats
+	}
+	tests := []struct {
+		name       string
+		rps        int
+		stats      [][]RequestStat
+		wantPassed bool
+		wantReason string //start of the reason
+	}{
+		{
+			name:       "no requests",
+			stats:      [][]RequestStat{{}},
+			wantPassed: false,
+		},
+		{
+			name: "fast and successful",
+			stats: [][]RequestStat{
+				{{Duration: 10 * time.Millisecond, StatusCode: 200}},
+				{{Duration: 20 * time.Millisecond, StatusCode: 200}},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "too slow",
+			stats: [][]RequestStat{
+				{{Duration: 10 * time.Millisecond, StatusCode: 200}},
+				{{Duration: time.Second, StatusCode: 200}},
+			},
+			wantPassed: false,
+		},
+		{
+			name: "too many 5xx",
+			stats: [][]RequestStat{
+				{{Duration: 10 * time.Millisecond, StatusCode: 200}},
+				{{Duration: 10 * time.Millisecond, StatusCode: 503}},
+			},
+			wantPassed: false,
+		},
+		{
+			name: "too many errors",
+			stats: [][]RequestStat{
+				{{Duration: 10 * time.Millisecond, StatusCode: 200}},
+				{{Error: http.ErrHandlerTimeout}},
+			},
+			wantPassed: false,
+		},
+		{
+			name:       "rate achieved by each target",
+			rps:        10,
+			stats:      [][]RequestStat{overASecond(11, 10*time.Millisecond, 0), overASecond(11, 10*time.Millisecond, 0)},
+			wantPassed: true,
+		},
+		{
+			name:       "rate not achieved",
+			rps:        20,
+			stats:      [][]RequestStat{overASecond(11, 10*time.Millisecond, 0)},
+			wantPassed: false,
+			wantReason: "achieved ",
+		},
+		{
+			name:       "client fell behind",
+			rps:        10,
+			stats:      [][]RequestStat{overASecond(11, 10*time.Millisecond, 490*time.Millisecond)},
+			wantPassed: false,
+			wantReason: "99th percentile response time 500ms",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rps := tc.rps
+			if rps == 0 {
+				rps = 1
+			}
+			passed, reason := checkSearchCriteria(s, rps, tc.stats)
+			if passed != tc.wantPassed || !strings.HasPrefix(reason, tc.wantReason) {
+				t.Errorf("got passed: %t (%s), wanted: %t (%s)", passed, reason, tc.wantPassed, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestValidateSearchConfig(t *testing.T) {
+	valid := func() SearchConfig { return *NewSearchConfig() }
+	tests := []struct {
+		name      string
+		config    func() SearchConfig
+		expectErr bool
+	}{
+		{name: "uninitialized", config: func() SearchConfig { return SearchConfig{} }, expectErr: true},
+		{name: "valid", config: valid, expectErr: false},
+		{name: "unknown mode", config: func() SearchConfig { s := valid(); s.Mode = "unknown"; return s }, expectErr: true},
+		{name: "zero min rps", config: func() SearchConfig { s := valid(); s.MinRPS = 0; return s }, expectErr: true},
+		{name: "max below min", config: func() SearchConfig { s := valid(); s.MaxRPS = s.MinRPS - 1; return s }, expectErr: true},
+		{name: "zero step", config: func() SearchConfig { s := valid(); s.Step = 0; return s }, expectErr: true},
+		{name: "zero duration", config: func() SearchConfig { s := valid(); s.Duration = 0; return s }, expectErr: true},
+		{name: "percentile over 100", config: func() SearchConfig { s := valid(); s.Percentile = 101; return s }, expectErr: true},
+		{name: "unparseable max latency", config: func() SearchConfig { s := valid(); s.MaxLatency = "slow"; return s }, expectErr: true},
+		{name: "negative max error rate", config: func() SearchConfig { s := valid(); s.MaxErrorRate = -1; return s }, expectErr: true},
+		{name: "unknown arrival", config: func() SearchConfig { s := valid(); s.Arrival = "unknown"; return s }, expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateSearchConfig(tc.config())
+			if (err != nil) != tc.expectErr {
+				t.Errorf("got error: %t, wanted: %t", (err != nil), tc.expectErr)
+			}
+		})
+	}
+}
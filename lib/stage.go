@@ -0,0 +1,175 @@
+package pewpew
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage is one period of a benchmark. The rate changes linearly from StartRPS
+// to EndRPS over the stage, so equal values hold the rate steady.
+type Stage struct {
+	//Duration is how long the stage lasts, such as "30s" or "5m"
+	Duration string
+	//StartRPS is the requests per second at the beginning of the stage
+	StartRPS int
+	//EndRPS is the requests per second at the end of the stage
+	EndRPS int
+}
+
+func (s Stage) String() string {
+	if s.StartRPS == s.EndRPS {
+		return fmt.Sprintf("%s at %d RPS", s.Duration, s.StartRPS)
+	}
+	return fmt.Sprintf("%s ramping %d to %d RPS", s.Duration, s.StartRPS, s.EndRPS)
+}
+
+// ParseStages parses a comma separated list of stages, each either
+// DURATION:RPS to hold a rate or DURATION:FROM-TO to ramp between rates.
+// For example "60s:0-500,5m:500,10s:2000,60s:2000-0" ramps up to 500 RPS over a minute,
+// holds for five minutes, spikes to 2000 RPS for ten seconds, then ramps down to zero.
+func ParseStages(str string) ([]Stage, error) {
+	var stages []Stage
+	for _, stageStr := range strings.Split(str, ",") {
+		parts := strings.SplitN(strings.TrimSpace(stageStr), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("failed to parse stage %q, expected DURATION:RPS or DURATION:FROM-TO", stageStr)
+		}
+		stage := Stage{Duration: strings.TrimSpace(parts[0])}
+		rates := strings.SplitN(parts[1], "-", 2)
+		var err error
+		stage.StartRPS, err = strconv.Atoi(strings.TrimSpace(rates[0]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RPS of stage %q: %w", stageStr, err)
+		}
+		stage.EndRPS = stage.StartRPS
+		if len(rates) == 2 {
+			stage.EndRPS, err = strconv.Atoi(strings.TrimSpace(rates[1]))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse RPS of stage %q: %w", stageStr, err)
+			}
+		}
+		if err := validateStage(stage); err != nil {
+			return nil, err
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+func validateStage(s Stage) error {
+	duration, err := time.ParseDuration(s.Duration)
+	if err != nil {
+		return errors.New("failed to parse stage duration: " + s.Duration)
+	}
+	if duration <= 0 {
+		return errors.New("stage duration must be greater than zero")
+	}
+	if s.StartRPS < 0 || s.EndRPS < 0 {
+		return errors.New("stage RPS cannot be negative")
+	}
+	return nil
+}
+
+// loadProfile is the planned request rate over the whole benchmark, built from Stages
+type loadProfile struct {
+	stages []profileStage
+	total  time.Duration
+}
+
+type profileStage struct {
+	start    time.Duration
+	duration time.Duration
+	startRPS float64
+	endRPS   float64
+	//expected number of requests sent before this stage starts
+	startCount float64
+}
+
+// newLoadProfile creates a loadProfile out of already validated stages
+func newLoadProfile(stages []Stage) *loadProfile {
+	lp := &loadProfile{}
+	var count float64
+	for _, s := range stages {
+		duration, _ := time.ParseDuration(s.Duration)
+		ps := profileStage{
+			start:      lp.total,
+			duration:   duration,
+			startRPS:   float64(s.StartRPS),
+			endRPS:     float64(s.EndRPS),
+			startCount: count,
+		}
+		lp.stages = append(lp.stages, ps)
+		lp.total += duration
+		count += ps.count(duration)
+	}
+	return lp
+}
+
+// count is the expected number of requests sent in the first elapsed part of the stage
+func (ps profileStage) count(elapsed time.Duration) float64 {
+	secs := elapsed.Seconds()
+	slope := (ps.endRPS - ps.startRPS) / ps.duration.Seconds()
+	return ps.startRPS*secs + slope*secs*secs/2
+}
+
+// elapsed is the inverse of count: how far into the stage the expected count reaches n
+func (ps profileStage) elapsed(n float64) time.Duration {
+	slope := (ps.endRPS - ps.startRPS) / ps.duration.Seconds()
+	var secs float64
+	if slope == 0 {
+		secs = n / ps.startRPS
+	} else {
+		//solve slope/2*secs^2 + startRPS*secs - n = 0
+		discriminant := ps.startRPS*ps.startRPS + 2*slope*n
+		if discriminant < 0 {
+			//only from floating point error at the very end of a ramp down
+			discriminant = 0
+		}
+		secs = (-ps.startRPS + math.Sqrt(discriminant)) / slope
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// duration is the length of the whole profile
+func (lp *loadProfile) duration() time.Duration {
+	return lp.total
+}
+
+// expectedCount is how many requests are expected to be sent by offset
+func (lp *loadProfile) expectedCount(offset time.Duration) float64 {
+	if offset <= 0 || len(lp.stages) == 0 {
+		return 0
+	}
+	if offset >= lp.total {
+		last := lp.stages[len(lp.stages)-1]
+		return last.startCount + last.count(last.duration)
+	}
+	ps := lp.stages[lp.stageAt(offset)]
+	return ps.startCount + ps.count(offset-ps.start)
+}
+
+// offsetOf is when the expected number of requests sent reaches n,
+// or false if that doesn't happen before the end of the profile
+func (lp *loadProfile) offsetOf(n float64) (time.Duration, bool) {
+	for _, ps := range lp.stages {
+		end := ps.startCount + ps.count(ps.duration)
+		if n < end {
+			return ps.start + ps.elapsed(n-ps.startCount), true
+		}
+	}
+	return 0, false
+}
+
+// stageAt is the index of the stage running at offset
+func (lp *loadProfile) stageAt(offset time.Duration) int {
+	for idx, ps := range lp.stages {
+		if offset < ps.start+ps.duration {
+			return idx
+		}
+	}
+	return len(lp.stages) - 1
+}
@@ -0,0 +1,122 @@
+package pewpew
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		str       string
+		want      []Stage
+		expectErr bool
+	}{
+		{
+			name:      "empty",
+			str:       "",
+			expectErr: true,
+		},
+		{
+			name: "constant",
+			str:  "30s:100",
+			want: []Stage{{Duration: "30s", StartRPS: 100, EndRPS: 100}},
+		},
+		{
+			name: "ramp",
+			str:  "1m:0-500",
+			want: []Stage{{Duration: "1m", StartRPS: 0, EndRPS: 500}},
+		},
+		{
+			name: "multiple stages, inconsistent whitespace",
+			str:  "60s:0-500, 5m:500 ,10s: 2000,60s:2000 - 0",
+			want: []Stage{
+				{Duration: "60s", StartRPS: 0, EndRPS: 500},
+				{Duration: "5m", StartRPS: 500, EndRPS: 500},
+				{Duration: "10s", StartRPS: 2000, EndRPS: 2000},
+				{Duration: "60s", StartRPS: 2000, EndRPS: 0},
+			},
+		},
+		{
+			name:      "missing rate",
+			str:       "30s",
+			expectErr: true,
+		},
+		{
+			name:      "unparseable duration",
+			str:       "forever:10",
+			expectErr: true,
+		},
+		{
+			name:      "zero duration",
+			str:       "0s:10",
+			expectErr: true,
+		},
+		{
+			name:      "unparseable rate",
+			str:       "30s:fast",
+			expectErr: true,
+		},
+		{
+			name:      "trailing comma",
+			str:       "30s:10,",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			stages, err := ParseStages(tc.str)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("got error: %t, wanted: %t", (err != nil), tc.expectErr)
+			}
+			if err == nil && !reflect.DeepEqual(stages, tc.want) {
+				t.Errorf("got stages: %v, wanted: %v", stages, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	lp := newLoadProfile([]Stage{
+		{Duration: "10s", StartRPS: 0, EndRPS: 100},
+		{Duration: "10s", StartRPS: 100, EndRPS: 100},
+		{Duration: "10s", StartRPS: 100, EndRPS: 0},
+	})
+	if lp.duration() != 30*time.Second {
+		t.Errorf("got duration %s, wanted 30s", lp.duration())
+	}
+
+	tests := []struct {
+		offset time.Duration
+		count  float64
+		stage  int
+	}{
+		{offset: 0, count: 0, stage: 0},
+		{offset: 5 * time.Second, count: 125, stage: 0},
+		{offset: 10 * time.Second, count: 500, stage: 1},
+		{offset: 20 * time.Second, count: 1500, stage: 2},
+		{offset: 30 * time.Second, count: 2000, stage: 2},
+		{offset: time.Minute, count: 2000, stage: 2},
+	}
+	for _, tc := range tests {
+		if got := lp.expectedCount(tc.offset); math.Abs(got-tc.count) > 1e-6 {
+			t.Errorf("got expected count %f at %s, wanted %f", got, tc.offset, tc.count)
+		}
+		if got := lp.stageAt(tc.offset); got != tc.stage {
+			t.Errorf("got stage %d at %s, wanted %d", got, tc.offset, tc.stage)
+		}
+		if tc.offset < lp.duration() {
+			got, ok := lp.offsetOf(tc.count)
+			if !ok || (got-tc.offset).Round(time.Millisecond) != 0 {
+				t.Errorf("got offset %s for count %f, wanted %s", got, tc.count, tc.offset)
+			}
+		}
+	}
+	if _, ok := lp.offsetOf(2000); ok {
+		t.Errorf("got an offset past the end of the profile")
+	}
+}
@@ -2,6 +2,7 @@ package pewpew
 
 import (
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -18,6 +19,34 @@ type RequestStat struct {
 	StatusCode      int   `json:"statusCode"`
 	Error           error `json:"error"`
 	DataTransferred int   //bytes
+
+	//Breakdown of where the time went. Phases that didn't happen,
+	//such as DNS and connecting on a reused connection, are zero.
+	DNSDuration     time.Duration `json:"dnsDuration"`
+	ConnectDuration time.Duration `json:"connectDuration"`
+	TLSDuration     time.Duration `json:"tlsDuration"`
+	//time from the request being written to the first byte of the response, i.e. server think time
+	TimeToFirstByte time.Duration `json:"timeToFirstByte"`
+	//time to download the response body after the headers arrived
+	TransferDuration time.Duration `json:"transferDuration"`
+
+	//index of the benchmark Stage the request was sent in
+	Stage int `json:"stage"`
+
+	//descriptions of the Target's response checks that this response failed
+	CheckFailures []string `json:"checkFailures"`
+
+	//whether the request was never sent because the benchmark's MaxInFlight
+	//limit was reached. StartTime is when it was scheduled to be sent.
+	Dropped bool `json:"dropped"`
+
+	//when the request was supposed to be sent. In a benchmark, this is from the
+	//schedule, so is earlier than StartTime when the client fell behind.
+	//Otherwise it is the same as StartTime.
+	IntendedStartTime time.Time `json:"intendedStartTime"`
+	//time from IntendedStartTime to EndTime. Unlike Duration, this includes any
+	//delay in sending the request, correcting for coordinated omission.
+	ResponseTime time.Duration `json:"responseTime"`
 }
 
 // RequestStatSummary is an aggregate statistical summary of a set of RequestStats
@@ -26,6 +55,12 @@ type RequestStatSummary struct {
 	avgDuration          time.Duration
 	maxDuration          time.Duration
 	minDuration          time.Duration
+	stdDevDuration       time.Duration
+	p50Duration          time.Duration
+	p90Duration          time.Duration
+	p95Duration          time.Duration
+	p99Duration          time.Duration
+	p999Duration         time.Duration
 	statusCodes          map[int]int //counts of each code
 	startTime            time.Time   //start of first request
 	endTime              time.Time   //end of last request
@@ -34,6 +69,18 @@ type RequestStatSummary struct {
 	minDataTransferred   int         //bytes
 	totalDataTransferred int         //bytes
 	errorCount           int
+	avgDNSDuration       time.Duration
+	avgConnectDuration   time.Duration
+	avgTLSDuration       time.Duration
+	avgTimeToFirstByte   time.Duration
+	avgTransferDuration  time.Duration
+	latencies            *latencyHistogram //durations of non-error requests
+	checkFailedCount     int               //responses that failed at least one check
+	checkFailures        map[string]int    //count of each failed check
+	droppedCount         int               //requests never sent because of MaxInFlight
+	avgResponseTime      time.Duration
+	maxResponseTime      time.Duration
+	responseTimes        *latencyHistogram //response times of non-error requests
 }
 
 // CreateRequestsStats creates a statistical summary out of the individual RequestStats
@@ -51,10 +98,19 @@ func CreateRequestsStats(requestStats []RequestStat) RequestStatSummary {
 		startTime:            requestStats[0].StartTime,
 		endTime:              requestStats[0].EndTime,
 		totalDataTransferred: 0,
+		latencies:            newLatencyHistogram(),
+		responseTimes:        newLatencyHistogram(),
 	}
-	var totalDurations time.Duration //total time of all requests (concurrent is counted)
+	var totalDurations time.Duration  //total time of all requests (concurrent is counted)
+	var totalSquaredDurations float64 //for standard deviation, float to avoid overflow
+	var totalDNS, totalConnect, totalTLS, totalFirstByte, totalTransfer time.Duration
+	var totalResponseTime time.Duration
 	nonErrCount := 0
 	for i := 0; i < len(requestStats); i++ {
+		if requestStats[i].Dropped {
+			summary.droppedCount++
+			continue
+		}
 		if requestStats[i].Error != nil {
 			summary.errorCount++
 			continue
@@ -73,6 +129,23 @@ func CreateRequestsStats(requestStats []RequestStat) RequestStatSummary {
 			summary.endTime = requestStats[i].EndTime
 		}
 		totalDurations += requestStats[i].Duration
+		totalSquaredDurations += float64(requestStats[i].Duration) * float64(requestStats[i].Duration)
+		summary.latencies.record(requestStats[i].Duration)
+		//stats without a response time, such as from older exports, weren't delayed
+		responseTime := requestStats[i].ResponseTime
+		if responseTime == 0 {
+			responseTime = requestStats[i].Duration
+		}
+		totalResponseTime += responseTime
+		if responseTime > summary.maxResponseTime {
+			summary.maxResponseTime = responseTime
+		}
+		summary.responseTimes.record(responseTime)
+		totalDNS += requestStats[i].DNSDuration
+		totalConnect += requestStats[i].ConnectDuration
+		totalTLS += requestStats[i].TLSDuration
+		totalFirstByte += requestStats[i].TimeToFirstByte
+		totalTransfer += requestStats[i].TransferDuration
 
 		if requestStats[i].DataTransferred > summary.maxDataTransferred {
 			summary.maxDataTransferred = requestStats[i].DataTransferred
@@ -83,6 +156,16 @@ func CreateRequestsStats(requestStats []RequestStat) RequestStatSummary {
 		summary.totalDataTransferred += requestStats[i].DataTransferred
 
 		summary.statusCodes[requestStats[i].StatusCode]++
+
+		if len(requestStats[i].CheckFailures) > 0 {
+			summary.checkFailedCount++
+			if summary.checkFailures == nil {
+				summary.checkFailures = make(map[string]int)
+			}
+			for _, failure := range requestStats[i].CheckFailures {
+				summary.checkFailures[failure]++
+			}
+		}
 	}
 	if nonErrCount == 0 {
 		summary.avgDuration = 0
@@ -98,8 +181,52 @@ func CreateRequestsStats(requestStats []RequestStat) RequestStatSummary {
 	newAvg, _ := time.ParseDuration(fmt.Sprintf("%d", avgNs) + "ns")
 	summary.avgDuration = newAvg
 
+	//population standard deviation
+	mean := float64(totalDurations) / float64(nonErrCount)
+	variance := totalSquaredDurations/float64(nonErrCount) - mean*mean
+	if variance > 0 {
+		summary.stdDevDuration = time.Duration(math.Sqrt(variance))
+	}
+	summary.p50Duration = summary.latencies.valueAtPercentile(50)
+	summary.p90Duration = summary.latencies.valueAtPercentile(90)
+	summary.p95Duration = summary.latencies.valueAtPercentile(95)
+	summary.p99Duration = summary.latencies.valueAtPercentile(99)
+	summary.p999Duration = summary.latencies.valueAtPercentile(99.9)
+
+	summary.avgResponseTime = totalResponseTime / time.Duration(nonErrCount)
+
+	summary.avgDNSDuration = totalDNS / time.Duration(nonErrCount)
+	summary.avgConnectDuration = totalConnect / time.Duration(nonErrCount)
+	summary.avgTLSDuration = totalTLS / time.Duration(nonErrCount)
+	summary.avgTimeToFirstByte = totalFirstByte / time.Duration(nonErrCount)
+	summary.avgTransferDuration = totalTransfer / time.Duration(nonErrCount)
+
 	summary.avgDataTransferred = summary.totalDataTransferred / nonErrCount
 
 	summary.avgRPS = float64(nonErrCount) / float64(summary.endTime.Sub(summary.startTime))
 	return summary
 }
+
+// requestCount is the total number of requests summarized, including failed ones
+func (s RequestStatSummary) requestCount() int {
+	count := s.errorCount
+	for _, codeCount := range s.statusCodes {
+		count += codeCount
+	}
+	return count
+}
+
+// errorRate is the fraction of requests, from 0 to 1, that failed or got a 5xx response
+func (s RequestStatSummary) errorRate() float64 {
+	total := s.requestCount()
+	if total == 0 {
+		return 0
+	}
+	failed := s.errorCount
+	for code, codeCount := range s.statusCodes {
+		if code >= 500 && code < 600 {
+			failed += codeCount
+		}
+	}
+	return float64(failed) / float64(total)
+}
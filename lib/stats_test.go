@@ -24,14 +24,23 @@ func TestCreateRequestsStats(t *testing.T) {
 				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200},
 			},
 			want: RequestStatSummary{
-				avgRPS:      0.000000000001,
-				avgDuration: 1000,
-				maxDuration: 1000,
-				minDuration: 1000,
-				startTime:   time.Unix(1000, 0),
-				endTime:     time.Unix(2000, 0),
-				statusCodes: map[int]int{200: 1},
-				errorCount:  0,
+				avgRPS:          0.000000000001,
+				avgDuration:     1000,
+				maxDuration:     1000,
+				minDuration:     1000,
+				p50Duration:     1000,
+				p90Duration:     1000,
+				p95Duration:     1000,
+				p99Duration:     1000,
+				p999Duration:    1000,
+				startTime:       time.Unix(1000, 0),
+				endTime:         time.Unix(2000, 0),
+				statusCodes:     map[int]int{200: 1},
+				errorCount:      0,
+				latencies:       histogramOf(1000),
+				avgResponseTime: 1000,
+				maxResponseTime: 1000,
+				responseTimes:   histogramOf(1000),
 			},
 		},
 		{
@@ -41,14 +50,23 @@ func TestCreateRequestsStats(t *testing.T) {
 				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200},
 			},
 			want: RequestStatSummary{
-				avgRPS:      0.000000000002,
-				avgDuration: 1000,
-				maxDuration: 1000,
-				minDuration: 1000,
-				startTime:   time.Unix(1000, 0),
-				endTime:     time.Unix(2000, 0),
-				statusCodes: map[int]int{200: 2},
-				errorCount:  0,
+				avgRPS:          0.000000000002,
+				avgDuration:     1000,
+				maxDuration:     1000,
+				minDuration:     1000,
+				p50Duration:     1000,
+				p90Duration:     1000,
+				p95Duration:     1000,
+				p99Duration:     1000,
+				p999Duration:    1000,
+				startTime:       time.Unix(1000, 0),
+				endTime:         time.Unix(2000, 0),
+				statusCodes:     map[int]int{200: 2},
+				errorCount:      0,
+				latencies:       histogramOf(1000, 1000),
+				avgResponseTime: 1000,
+				maxResponseTime: 1000,
+				responseTimes:   histogramOf(1000, 1000),
 			},
 		},
 		{
@@ -58,14 +76,16 @@ func TestCreateRequestsStats(t *testing.T) {
 				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, Error: errors.New("test error 1")},
 			},
 			want: RequestStatSummary{
-				avgRPS:      0,
-				avgDuration: 0,
-				maxDuration: 0,
-				minDuration: 0,
-				startTime:   time.Unix(1000, 0),
-				endTime:     time.Unix(2000, 0),
-				statusCodes: map[int]int{},
-				errorCount:  2,
+				avgRPS:        0,
+				avgDuration:   0,
+				maxDuration:   0,
+				minDuration:   0,
+				startTime:     time.Unix(1000, 0),
+				endTime:       time.Unix(2000, 0),
+				statusCodes:   map[int]int{},
+				errorCount:    2,
+				latencies:     histogramOf(),
+				responseTimes: histogramOf(),
 			},
 		},
 		{
@@ -84,6 +104,12 @@ func TestCreateRequestsStats(t *testing.T) {
 				avgDuration:          1500,
 				maxDuration:          2000,
 				minDuration:          1000,
+				stdDevDuration:       500,
+				p50Duration:          1000,
+				p90Duration:          2000,
+				p95Duration:          2000,
+				p99Duration:          2000,
+				p999Duration:         2000,
 				startTime:            time.Unix(1000, 0),
 				endTime:              time.Unix(7000, 0),
 				statusCodes:          map[int]int{200: 2, 400: 4},
@@ -92,6 +118,10 @@ func TestCreateRequestsStats(t *testing.T) {
 				minDataTransferred:   100,
 				totalDataTransferred: 2100,
 				errorCount:           1,
+				latencies:            histogramOf(1000, 1000, 1000, 2000, 2000, 2000),
+				avgResponseTime:      1500,
+				maxResponseTime:      2000,
+				responseTimes:        histogramOf(1000, 1000, 1000, 2000, 2000, 2000),
 			},
 		},
 		{
@@ -110,6 +140,12 @@ func TestCreateRequestsStats(t *testing.T) {
 				avgDuration:          1500,
 				maxDuration:          2000,
 				minDuration:          1000,
+				stdDevDuration:       500,
+				p50Duration:          1000,
+				p90Duration:          2000,
+				p95Duration:          2000,
+				p99Duration:          2000,
+				p999Duration:         2000,
 				startTime:            time.Unix(1000, 0),
 				endTime:              time.Unix(7000, 0),
 				statusCodes:          map[int]int{200: 2, 400: 4},
@@ -118,6 +154,126 @@ func TestCreateRequestsStats(t *testing.T) {
 				minDataTransferred:   100,
 				totalDataTransferred: 2100,
 				errorCount:           1,
+				latencies:            histogramOf(1000, 1000, 1000, 2000, 2000, 2000),
+				avgResponseTime:      1500,
+				maxResponseTime:      2000,
+				responseTimes:        histogramOf(1000, 1000, 1000, 2000, 2000, 2000),
+			},
+		},
+		{
+			name: "timing breakdown",
+			requestStats: []RequestStat{
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200,
+					DNSDuration: 100, ConnectDuration: 200, TLSDuration: 300, TimeToFirstByte: 400, TransferDuration: 50},
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200,
+					TimeToFirstByte: 800, TransferDuration: 150},
+			},
+			want: RequestStatSummary{
+				avgRPS:              0.000000000002,
+				avgDuration:         1000,
+				maxDuration:         1000,
+				minDuration:         1000,
+				p50Duration:         1000,
+				p90Duration:         1000,
+				p95Duration:         1000,
+				p99Duration:         1000,
+				p999Duration:        1000,
+				startTime:           time.Unix(1000, 0),
+				endTime:             time.Unix(2000, 0),
+				statusCodes:         map[int]int{200: 2},
+				avgDNSDuration:      50,
+				avgConnectDuration:  100,
+				avgTLSDuration:      150,
+				avgTimeToFirstByte:  600,
+				avgTransferDuration: 100,
+				latencies:           histogramOf(1000, 1000),
+				avgResponseTime:     1000,
+				maxResponseTime:     1000,
+				responseTimes:       histogramOf(1000, 1000),
+			},
+		},
+		{
+			name: "check failures",
+			requestStats: []RequestStat{
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200,
+					CheckFailures: []string{"missing header ETag", "body is not valid JSON"}},
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200,
+					CheckFailures: []string{"missing header ETag"}},
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200},
+			},
+			want: RequestStatSummary{
+				avgRPS:           0.000000000003,
+				avgDuration:      1000,
+				maxDuration:      1000,
+				minDuration:      1000,
+				p50Duration:      1000,
+				p90Duration:      1000,
+				p95Duration:      1000,
+				p99Duration:      1000,
+				p999Duration:     1000,
+				startTime:        time.Unix(1000, 0),
+				endTime:          time.Unix(2000, 0),
+				statusCodes:      map[int]int{200: 3},
+				latencies:        histogramOf(1000, 1000, 1000),
+				avgResponseTime:  1000,
+				maxResponseTime:  1000,
+				responseTimes:    histogramOf(1000, 1000, 1000),
+				checkFailedCount: 2,
+				checkFailures:    map[string]int{"missing header ETag": 2, "body is not valid JSON": 1},
+			},
+		},
+		{
+			name: "dropped requests",
+			requestStats: []RequestStat{
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200},
+				{StartTime: time.Unix(1500, 0), EndTime: time.Unix(1500, 0), Dropped: true},
+				{StartTime: time.Unix(1500, 0), EndTime: time.Unix(1500, 0), Dropped: true},
+			},
+			want: RequestStatSummary{
+				avgRPS:          0.000000000001,
+				avgDuration:     1000,
+				maxDuration:     1000,
+				minDuration:     1000,
+				p50Duration:     1000,
+				p90Duration:     1000,
+				p95Duration:     1000,
+				p99Duration:     1000,
+				p999Duration:    1000,
+				startTime:       time.Unix(1000, 0),
+				endTime:         time.Unix(2000, 0),
+				statusCodes:     map[int]int{200: 1},
+				latencies:       histogramOf(1000),
+				avgResponseTime: 1000,
+				maxResponseTime: 1000,
+				responseTimes:   histogramOf(1000),
+				droppedCount:    2,
+			},
+		},
+		{
+			name: "requests sent late",
+			requestStats: []RequestStat{
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200,
+					IntendedStartTime: time.Unix(1000, 0), ResponseTime: 1000},
+				{StartTime: time.Unix(1000, 0), EndTime: time.Unix(2000, 0), Duration: 1000, StatusCode: 200,
+					IntendedStartTime: time.Unix(999, 0), ResponseTime: 3000},
+			},
+			want: RequestStatSummary{
+				avgRPS:          0.000000000002,
+				avgDuration:     1000,
+				maxDuration:     1000,
+				minDuration:     1000,
+				p50Duration:     1000,
+				p90Duration:     1000,
+				p95Duration:     1000,
+				p99Duration:     1000,
+				p999Duration:    1000,
+				startTime:       time.Unix(1000, 0),
+				endTime:         time.Unix(2000, 0),
+				statusCodes:     map[int]int{200: 2},
+				latencies:       histogramOf(1000, 1000),
+				avgResponseTime: 2000,
+				maxResponseTime: 3000,
+				responseTimes:   histogramOf(1000, 3000),
 			},
 		},
 	}
@@ -1,25 +1,41 @@
 package pewpew
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"time"
 )
 
 type workerDone struct{}
 
+// targetResult is all of the RequestStats of the Target at idx
+type targetResult struct {
+	idx   int
+	stats []RequestStat
+}
+
 type (
 	//StressConfig is the top level struct that contains the configuration for a stress test
 	StressConfig struct {
 		Verbose bool
 		Quiet   bool
 
-		//Count is how many total requests to make for each Target
+		//Count is how many total requests to make for each Target.
+		//Zero means no limit, as long as Duration is set.
 		Count int
+		//Duration is the number of seconds to keep making requests for each Target.
+		//Zero means no limit, as long as Count is set. When both are set,
+		//the test stops at whichever is reached first.
+		Duration int
 		//Concurrency is how many requests can be happening simultaneously for each Target
 		Concurrency int
 		Targets     []Target
+		//Thresholds are pass/fail conditions checked against the summary of all Targets combined
+		Thresholds []string
+		//Observers are notified about each request as the test runs
+		Observers []Observer
 
 		//global target settings
 		Options TargetOptions
@@ -50,6 +66,13 @@ func NewStressConfig() (s *StressConfig) {
 // RunStress starts the stress tests with the provided StressConfig.
 // Throughout the test, data is sent to w, useful for live updates.
 func RunStress(s StressConfig, w io.Writer) ([][]RequestStat, error) {
+	return RunStressContext(context.Background(), s, w)
+}
+
+// RunStressContext is RunStress, but stops early when ctx is done.
+// Requests in flight are aborted and the RequestStats of the requests
+// completed so far are returned, along with ctx's error.
+func RunStressContext(ctx context.Context, s StressConfig, w io.Writer) ([][]RequestStat, error) {
 	if w == nil {
 		return nil, errors.New("nil writer")
 	}
@@ -63,9 +86,15 @@ func RunStress(s StressConfig, w io.Writer) ([][]RequestStat, error) {
 	p := printer{output: w}
 
 	//setup the queue of requests, one queue per target
-	requestQueues := make([](chan http.Request), targetCount)
+	//when the duration is up, the queues stop but requests in flight are left to finish
+	queueCtx, stopQueues := context.WithCancel(ctx)
+	if s.Duration > 0 {
+		queueCtx, stopQueues = context.WithTimeout(ctx, time.Duration(s.Duration)*time.Second)
+	}
+	defer stopQueues()
+	requestQueues := make([](chan queuedRequest), targetCount)
 	for idx, target := range s.Targets {
-		requestQueue, err := createRequestQueue(s.Count, target)
+		requestQueue, err := createRequestQueue(queueCtx, s.Count, target)
 		if err != nil {
 			return nil, err
 		}
@@ -79,25 +108,39 @@ func RunStress(s StressConfig, w io.Writer) ([][]RequestStat, error) {
 	}
 
 	//when a target is finished, send all stats into this
-	targetStats := make(chan []RequestStat)
+	targetStats := make(chan targetResult)
 	for idx, target := range s.Targets {
-		go func(target Target, requestQueue chan http.Request, targetStats chan []RequestStat) {
-			p.writeString(fmt.Sprintf("- Running %d tests at %s, %d at a time\n", s.Count, target.URL, s.Concurrency))
+		go func(idx int, target Target, requestQueue chan queuedRequest, targetStats chan targetResult) {
+			switch {
+			case s.Duration <= 0:
+				p.writeString(fmt.Sprintf("- Running %d tests at %s, %d at a time\n", s.Count, target.URL, s.Concurrency))
+			case s.Count <= 0:
+				p.writeString(fmt.Sprintf("- Running tests at %s for %d seconds, %d at a time\n", target.URL, s.Duration, s.Concurrency))
+			default:
+				p.writeString(fmt.Sprintf("- Running up to %d tests at %s for up to %d seconds, %d at a time\n", s.Count, target.URL, s.Duration, s.Concurrency))
+			}
 
 			workerDoneChan := make(chan workerDone)   //workers use this to indicate they are done
 			requestStatChan := make(chan RequestStat) //workers communicate each requests' info
 
 			client := createClient(target)
+			//already validated
+			checks, _ := newResponseChecks(target.Options)
 
 			//start up the workers
 			for i := 0; i < s.Concurrency; i++ {
 				go func() {
-					for req := range requestQueue {
-						response, stat := runRequest(req, client)
+					for queued := range requestQueue {
+						notifyStarted(s.Observers, idx)
+						response, stat := queued.send(ctx, target, client, checks)
+						notifyFinished(s.Observers, idx, stat)
+						if abortedByCancel(ctx, stat) {
+							continue
+						}
 						if !s.Quiet {
 							p.printStat(stat)
 							if s.Verbose {
-								p.printVerbose(&req, response)
+								p.printVerbose(&queued.req, response)
 							}
 						}
 						requestStatChan <- stat
@@ -105,8 +148,7 @@ func RunStress(s StressConfig, w io.Writer) ([][]RequestStat, error) {
 					workerDoneChan <- workerDone{}
 				}()
 			}
-			requestStats := make([]RequestStat, s.Count)
-			requestsCompleteCount := 0
+			requestStats := make([]RequestStat, 0, s.Count)
 			workersDoneCount := 0
 			//wait for all workers to finish
 			for {
@@ -114,21 +156,20 @@ func RunStress(s StressConfig, w io.Writer) ([][]RequestStat, error) {
 				case <-workerDoneChan:
 					workersDoneCount++
 				case stat := <-requestStatChan:
-					requestStats[requestsCompleteCount] = stat
-					requestsCompleteCount++
+					requestStats = append(requestStats, stat)
 				}
 				if workersDoneCount == s.Concurrency {
 					//all workers are finished
 					break
 				}
 			}
-			targetStats <- requestStats
-		}(target, requestQueues[idx], targetStats)
+			targetStats <- targetResult{idx: idx, stats: requestStats}
+		}(idx, target, requestQueues[idx], targetStats)
 	}
 	targetRequestStats := make([][]RequestStat, targetCount)
 	targetDoneCount := 0
-	for reqStats := range targetStats {
-		targetRequestStats[targetDoneCount] = reqStats
+	for result := range targetStats {
+		targetRequestStats[result.idx] = result.stats
 		targetDoneCount++
 		if targetDoneCount == targetCount {
 			//all targets are finished
@@ -136,22 +177,31 @@ func RunStress(s StressConfig, w io.Writer) ([][]RequestStat, error) {
 		}
 	}
 
-	return targetRequestStats, nil
+	return targetRequestStats, ctx.Err()
 }
 
 func validateStressConfig(s StressConfig) error {
 	if len(s.Targets) == 0 {
 		return errors.New("zero targets")
 	}
-	if s.Count <= 0 {
-		return errors.New("request count must be greater than zero")
+	if s.Count < 0 {
+		return errors.New("request count cannot be negative")
+	}
+	if s.Duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+	if s.Count == 0 && s.Duration == 0 {
+		return errors.New("request count or duration must be greater than zero")
 	}
 	if s.Concurrency <= 0 {
 		return errors.New("concurrency must be greater than zero")
 	}
-	if s.Concurrency > s.Count {
+	if s.Count > 0 && s.Concurrency > s.Count {
 		return errors.New("concurrency must be higher than request count")
 	}
+	if err := validateThresholds(s.Thresholds); err != nil {
+		return err
+	}
 
 	for _, target := range s.Targets {
 		if err := validateTarget(target); err != nil {
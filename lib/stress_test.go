@@ -1,10 +1,16 @@
 package pewpew
 
 import (
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 const tempFilename = "/tmp/testdata"
@@ -303,6 +309,129 @@ func TestRunStress(t *testing.T) {
 	}
 }
 
+func TestRunStressContext(t *testing.T) {
+	//server is slow enough that the stress test can't finish before being cancelled
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	s := StressConfig{
+		Count:       1000,
+		Concurrency: 2,
+		Quiet:       true,
+		Targets: []Target{
+			{
+				URL: server.URL,
+				Options: TargetOptions{
+					Method: "GET",
+				},
+			},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	stats, err := RunStressContext(ctx, s, ioutil.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error: %v, wanted: %v", err, context.DeadlineExceeded)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("got stats for %d targets, wanted 1", len(stats))
+	}
+	if len(stats[0]) == 0 || len(stats[0]) >= s.Count {
+		t.Errorf("got %d partial results, wanted some but fewer than %d", len(stats[0]), s.Count)
+	}
+	for _, stat := range stats[0] {
+		if stat.Error != nil {
+			t.Errorf("got aborted request in results: %s", stat.Error)
+		}
+	}
+}
+
+func TestRunStressDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		count       int
+		duration    int
+		wantCount   int //zero means any number of requests
+		maxDuration time.Duration
+	}{
+		{name: "duration only", count: 0, duration: 1, maxDuration: 2 * time.Second},
+		{name: "count reached first", count: 4, duration: 10, wantCount: 4, maxDuration: 2 * time.Second},
+		{name: "duration reached first", count: 1000000, duration: 1, maxDuration: 2 * time.Second},
+	}
+	for _, tc := range tests {
+		s := StressConfig{
+			Count:       tc.count,
+			Duration:    tc.duration,
+			Concurrency: 2,
+			Quiet:       true,
+			Targets: []Target{
+				{
+					URL: server.URL,
+					Options: TargetOptions{
+						Method: "GET",
+					},
+				},
+			},
+		}
+		start := time.Now()
+		stats, err := RunStress(s, ioutil.Discard)
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("%s: got error: %s", tc.name, err)
+		}
+		if elapsed > tc.maxDuration {
+			t.Errorf("%s: took %s, wanted less than %s", tc.name, elapsed, tc.maxDuration)
+		}
+		if tc.wantCount > 0 && len(stats[0]) != tc.wantCount {
+			t.Errorf("%s: got %d requests, wanted %d", tc.name, len(stats[0]), tc.wantCount)
+		}
+		if len(stats[0]) == 0 {
+			t.Errorf("%s: got no requests", tc.name)
+		}
+		//requests in flight when the duration ran out are left to finish
+		for _, stat := range stats[0] {
+			if stat.Error != nil {
+				t.Errorf("%s: got failed request: %s", tc.name, stat.Error)
+			}
+		}
+	}
+}
+
+func TestRunStressObservers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	s := StressConfig{
+		Count:       10,
+		Concurrency: 2,
+		Quiet:       true,
+		Targets:     []Target{{URL: server.URL, Options: TargetOptions{Method: "GET"}}},
+	}
+	m := NewMetrics(s.Targets)
+	s.Observers = []Observer{m}
+	_, err := RunStress(s, ioutil.Discard)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+	var b strings.Builder
+	m.write(&b)
+	for _, want := range []string{`code="200"} 10`, "pewpew_requests_in_flight{" + m.targets[0].labels + "} 0"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics are missing %s:\n%s", want, b.String())
+		}
+	}
+}
+
 func TestValidateStressConfig(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -348,6 +477,60 @@ func TestValidateStressConfig(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "duration without count",
+			s: StressConfig{
+				Count:       0,
+				Duration:    10,
+				Concurrency: 20,
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "negative duration",
+			s: StressConfig{
+				Count:       DefaultCount,
+				Duration:    -1,
+				Concurrency: DefaultConcurrency,
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative count",
+			s: StressConfig{
+				Count:       -1,
+				Duration:    10,
+				Concurrency: DefaultConcurrency,
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
 		{
 			name: "concurrency > count",
 			s: StressConfig{
@@ -365,6 +548,61 @@ func TestValidateStressConfig(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "invalid global threshold",
+			s: StressConfig{
+				Count:       DefaultCount,
+				Concurrency: DefaultConcurrency,
+				Thresholds:  []string{"p95 ~ 300ms"},
+				Targets: []Target{
+					{
+						URL: DefaultURL,
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid target threshold",
+			s: StressConfig{
+				Count:       DefaultCount,
+				Concurrency: DefaultConcurrency,
+				Targets: []Target{
+					{
+						URL:        DefaultURL,
+						Thresholds: []string{"latency < 300ms"},
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid thresholds",
+			s: StressConfig{
+				Count:       DefaultCount,
+				Concurrency: DefaultConcurrency,
+				Thresholds:  []string{"error-rate < 1%"},
+				Targets: []Target{
+					{
+						URL:        DefaultURL,
+						Thresholds: []string{"p95 < 300ms", "status-5xx == 0"},
+						Options: TargetOptions{
+							Timeout: DefaultTimeout,
+							Method:  DefaultMethod,
+						},
+					},
+				},
+			},
+			expectErr: false,
+		},
 		{
 			name: "empty method",
 			s: StressConfig{
@@ -15,6 +15,16 @@ const (
 	DefaultConcurrency = 1
 	DefaultRPS         = 10
 	DefaultDuration    = 15
+	DefaultArrival     = ArrivalBurst
+
+	DefaultSearchMode    = SearchBinary
+	DefaultMinRPS        = 1
+	DefaultMaxRPS        = 1000
+	DefaultStep          = 10
+	DefaultPercentile    = 99
+	DefaultMaxLatency    = "1s"
+	DefaultMaxErrorRate  = 1.0
+	DefaultProbeDuration = 10
 )
 
 // Target is location of where send the HTTP request and how to send it.
@@ -23,8 +33,32 @@ type Target struct {
 	//Whether or not to interpret the URL as a regular expression string
 	//and generate actual target URLs from that
 	RegexURL bool
+	//Whether or not to interpret the URL, Body, Headers, Cookies, BasicAuth and RawHeaders
+	//as Go text/template templates, executed for each request. The functions
+	//uuid, now, seq, randInt, randChoice, base64 and env are available.
+	Template bool
+	//RequestFile is a JSON Lines file of requests to send instead of building
+	//them from URL, one ReplayRequest per line. The Options still apply, with
+	//the method, body and headers of each request taking precedence. When set,
+	//URL is only used to describe the Target, so can be empty.
+	RequestFile string
+	//RequestOrder is the order the RequestFile's requests are sent in, one of
+	//RequestOrderRoundRobin, RequestOrderOrdered, or RequestOrderRandom
+	RequestOrder string
+	//DataFile is a CSV file, with a header line, or a JSON file, with an array
+	//of objects, of rows of data for the requests. Each request's URL, Body,
+	//Headers, Cookies, BasicAuth and RawHeaders are executed as templates with
+	//a row as their data, so a column is used like {{.id}}.
+	DataFile string
+	//DataOrder is the order the DataFile's rows are used in, one of
+	//DataOrderSequential, DataOrderRandom, or DataOrderUnique
+	DataOrder string
+	//Thresholds are pass/fail conditions checked against this Target's summary,
+	//such as "p95 < 300ms". See Threshold for the syntax.
+	Thresholds []string
 
-	Options TargetOptions
+	//squashed so that options are set directly on a target in a config file
+	Options TargetOptions `mapstructure:",squash"`
 }
 
 // TargetOptions is the configuration for a Target
@@ -41,8 +75,11 @@ type TargetOptions struct {
 	// and generate actual body from that
 	RegexBody bool
 	//A location on disk to read the HTTP body from. Empty string means it will not be read.
-	BodyFilename    string
-	Headers         string
+	BodyFilename string
+	Headers      string
+	//Headers to set as is, without parsing, so values can contain commas.
+	//They take precedence over Headers and UserAgent.
+	RawHeaders      map[string]string
 	Cookies         string
 	UserAgent       string
 	BasicAuth       string
@@ -51,15 +88,43 @@ type TargetOptions struct {
 	FollowRedirects bool
 	NoHTTP2         bool
 	EnforceSSL      bool
+
+	//Response checks. A response that fails any of them still counts as a
+	//response, but is recorded in RequestStat.CheckFailures.
+
+	//Comma separated status codes or classes the response must have, eg. "200,201,3xx"
+	ExpectStatus string
+	//Comma separated headers the response must have, as NAME or NAME:VALUE
+	//where the header's value must contain VALUE, eg. "Content-Type:application/json, ETag"
+	ExpectHeaders string
+	//Regular expression the response body must match
+	ExpectBodyRegex string
+	//Regular expression the response body must not match
+	RejectBodyRegex string
+	//JSON paths the response body must have, as PATH or PATH=VALUE,
+	//eg. "data.items.0.id" or "status=ok"
+	ExpectJSON []string
 }
 
 func validateTarget(target Target) error {
-	if target.URL == "" {
+	if target.URL == "" && target.RequestFile == "" {
 		return errors.New("empty URL")
 	}
+	if err := validateRequestOrder(target.RequestOrder); err != nil {
+		return err
+	}
+	if err := validateDataOrder(target.DataOrder); err != nil {
+		return err
+	}
 	if target.Options.Method == "" {
 		return errors.New("method cannot be empty string")
 	}
+	if err := validateThresholds(target.Thresholds); err != nil {
+		return err
+	}
+	if _, err := newResponseChecks(target.Options); err != nil {
+		return err
+	}
 	if target.Options.Timeout != "" {
 		timeout, err := time.ParseDuration(target.Options.Timeout)
 		if err != nil {
@@ -59,6 +59,39 @@ func TestValidateTarget(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "request file without URL",
+			t: Target{
+				RequestFile: "requests.jsonl",
+				Options: TargetOptions{
+					Method: DefaultMethod,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "unknown request order",
+			t: Target{
+				RequestFile:  "requests.jsonl",
+				RequestOrder: "backwards",
+				Options: TargetOptions{
+					Method: DefaultMethod,
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "unknown data order",
+			t: Target{
+				URL:       DefaultURL,
+				DataFile:  "users.csv",
+				DataOrder: "shuffled",
+				Options: TargetOptions{
+					Method: DefaultMethod,
+				},
+			},
+			expectErr: true,
+		},
 	}
 	for _, tc := range tests {
 		tc := tc
@@ -0,0 +1,181 @@
+package pewpew
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"text/template"
+	"time"
+)
+
+// requestTemplate renders the templates of a Target with Template or a
+// DataFile set, once for each request. It isn't safe for concurrent use.
+type requestTemplate struct {
+	//parsed templates, by their text
+	templates map[string]*template.Template
+	funcs     template.FuncMap
+	rng       *rand.Rand
+	//feeder picks the row of the DataFile that is the templates' data, if set
+	feeder *dataFeeder
+	//seq is the number of the request being rendered, from 1
+	seq int64
+}
+
+func newRequestTemplate(target Target) (*requestTemplate, error) {
+	r := &requestTemplate{
+		templates: make(map[string]*template.Template),
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if target.DataFile != "" {
+		feeder, err := newDataFeeder(target.DataFile, target.DataOrder)
+		if err != nil {
+			return nil, err
+		}
+		r.feeder = feeder
+	}
+	r.funcs = template.FuncMap{
+		"uuid":       r.uuid,
+		"now":        time.Now,
+		"seq":        func() int64 { return r.seq },
+		"randInt":    r.randInt,
+		"randChoice": r.randChoice,
+		"base64":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"env":        os.Getenv,
+	}
+	return r, nil
+}
+
+// render returns t with the templates of its URL, Body, Headers, Cookies,
+// BasicAuth and RawHeaders executed for the next request, or false once the
+// DataFile has no rows left
+func (r *requestTemplate) render(t Target) (Target, bool, error) {
+	row := map[string]string{}
+	if r.feeder != nil {
+		var ok bool
+		if row, ok = r.feeder.next(); !ok {
+			return Target{}, false, nil
+		}
+	}
+	r.seq++
+	t, err := r.renderRow(t, row)
+	return t, true, err
+}
+
+// renderRow returns t with its templates executed with row as their data
+func (r *requestTemplate) renderRow(t Target, row map[string]string) (Target, error) {
+	fields := []struct {
+		name string
+		text *string
+	}{
+		{"URL", &t.URL},
+		{"body", &t.Options.Body},
+		{"headers", &t.Options.Headers},
+		{"cookies", &t.Options.Cookies},
+		{"basic auth", &t.Options.BasicAuth},
+	}
+	for _, f := range fields {
+		rendered, err := r.execute(*f.text, row)
+		if err != nil {
+			return Target{}, fmt.Errorf("%s template: %w", f.name, err)
+		}
+		*f.text = rendered
+	}
+	if len(t.Options.RawHeaders) > 0 {
+		headers := make(map[string]string, len(t.Options.RawHeaders))
+		for key, val := range t.Options.RawHeaders {
+			rendered, err := r.execute(val, row)
+			if err != nil {
+				return Target{}, fmt.Errorf("header %s template: %w", key, err)
+			}
+			headers[key] = rendered
+		}
+		t.Options.RawHeaders = headers
+	}
+	return t, nil
+}
+
+// parseTarget checks that the templates of t parse, before any are executed
+func (r *requestTemplate) parseTarget(t Target) error {
+	fields := map[string]string{
+		"URL":        t.URL,
+		"body":       t.Options.Body,
+		"headers":    t.Options.Headers,
+		"cookies":    t.Options.Cookies,
+		"basic auth": t.Options.BasicAuth,
+	}
+	for key, val := range t.Options.RawHeaders {
+		fields["header "+key] = val
+	}
+	for name, text := range fields {
+		if _, err := r.parse(text); err != nil {
+			return fmt.Errorf("%s template: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// reset starts over the request numbers and rows, after trial requests
+func (r *requestTemplate) reset() {
+	r.seq = 0
+	if r.feeder != nil {
+		r.feeder.pos = 0
+	}
+}
+
+// execute runs the template text with a row of data
+func (r *requestTemplate) execute(text string, row map[string]string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := r.parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, row); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parse parses the template text the first time it's seen
+func (r *requestTemplate) parse(text string) (*template.Template, error) {
+	if tmpl, ok := r.templates[text]; ok {
+		return tmpl, nil
+	}
+	//a misspelled column is an error, rather than sending "<no value>"
+	tmpl, err := template.New("").Funcs(r.funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	r.templates[text] = tmpl
+	return tmpl, nil
+}
+
+// uuid creates a random version 4 UUID
+func (r *requestTemplate) uuid() string {
+	var b [16]byte
+	r.rng.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// randInt picks an integer from min to max, inclusive
+func (r *requestTemplate) randInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("randInt max %d is less than min %d", max, min)
+	}
+	return min + r.rng.Intn(max-min+1), nil
+}
+
+// randChoice picks one of choices
+func (r *requestTemplate) randChoice(choices ...interface{}) (interface{}, error) {
+	if len(choices) == 0 {
+		return nil, errors.New("randChoice needs at least one choice")
+	}
+	return choices[r.rng.Intn(len(choices))], nil
+}
@@ -0,0 +1,170 @@
+package pewpew
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTemplateRender(t *testing.T) {
+	os.Setenv("PEWPEW_TEMPLATE_TEST", "secret")
+	t.Cleanup(func() { os.Unsetenv("PEWPEW_TEMPLATE_TEST") })
+
+	tests := []struct {
+		name      string
+		text      string
+		want      string //regular expression the result must match
+		expectErr bool
+	}{
+		{name: "plain text", text: "http://localhost/a", want: `^http://localhost/a$`},
+		{name: "uuid", text: "{{uuid}}", want: `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{name: "now", text: "{{now.Unix}}", want: `^[0-9]{10}$`},
+		{name: "now format", text: `{{now.Format "2006"}}`, want: `^` + strconv.Itoa(time.Now().Year()) + `$`},
+		{name: "seq", text: "id={{seq}}&again={{seq}}", want: `^id=1&again=1$`},
+		{name: "randInt", text: "{{randInt 5 7}}", want: `^[5-7]$`},
+		{name: "randChoice", text: `{{randChoice "a" "b"}}`, want: `^(a|b)$`},
+		{name: "base64", text: `{{base64 "user:pass"}}`, want: `^dXNlcjpwYXNz$`},
+		{name: "env", text: `{{env "PEWPEW_TEMPLATE_TEST"}}`, want: `^secret$`},
+		{name: "parse error", text: "{{uuid", expectErr: true},
+		{name: "unknown function", text: "{{nope}}", expectErr: true},
+		{name: "randInt range", text: "{{randInt 7 5}}", expectErr: true},
+		{name: "randChoice empty", text: "{{randChoice}}", expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			target := Target{URL: tc.text, Template: true}
+			tmpl, err := newRequestTemplate(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered, _, err := tmpl.render(target)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if !regexp.MustCompile(tc.want).MatchString(rendered.URL) {
+				t.Errorf("got %q, wanted a match of %s", rendered.URL, tc.want)
+			}
+		})
+	}
+}
+
+func TestTemplateRequestSource(t *testing.T) {
+	target := Target{
+		URL:      "http://localhost/items/{{seq}}",
+		Template: true,
+		Options: TargetOptions{
+			Method:     "POST",
+			Body:       `{"n": {{seq}}}`,
+			Headers:    "X-Seq:{{seq}}",
+			Cookies:    "seq={{seq}}",
+			RawHeaders: map[string]string{"X-Raw": "a, {{seq}}"},
+		},
+	}
+	source, err := newRequestSource(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the trial request doesn't use up a number
+	for i := 1; i <= 3; i++ {
+		req, ok, err := source.next()
+		if err != nil || !ok {
+			t.Fatalf("got error %v, ok %t", err, ok)
+		}
+		n := strconv.Itoa(i)
+		body, _ := ioutil.ReadAll(req.Body)
+		cookie, _ := req.Cookie("seq")
+		if req.URL.Path != "/items/"+n || string(body) != `{"n": `+n+`}` || req.Header.Get("X-Seq") != n ||
+			req.Header.Get("X-Raw") != "a, "+n || cookie == nil || cookie.Value != n {
+			t.Errorf("got request %d with path %s, body %s, headers %v", i, req.URL.Path, body, req.Header)
+		}
+	}
+
+	//templates are only executed when enabled
+	target.Template = false
+	req, err := buildRequest(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("X-Raw") != "a, {{seq}}" {
+		t.Errorf("got X-Raw %q from a target that isn't a template", req.Header.Get("X-Raw"))
+	}
+
+	if _, err := newRequestSource(Target{URL: "http://localhost/{{", Template: true, Options: TargetOptions{Method: "GET"}}); err == nil {
+		t.Error("got no error for an invalid template")
+	}
+}
+
+func TestTemplateFailsAfterFirstRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	//the subtests are parallel, so are still running when this returns
+	t.Cleanup(server.Close)
+
+	//each template renders for the trial request, so only fails once running
+	tests := []struct {
+		name       string
+		target     Target
+		wantFailed int
+		wantErr    string
+	}{
+		{
+			name:       "seq dependent",
+			target:     Target{URL: server.URL + "/{{if gt seq 2}}{{randInt 2 1}}{{end}}", Template: true},
+			wantFailed: 3,
+			wantErr:    "randInt max 1 is less than min 2",
+		},
+		{
+			name:       "computed bounds",
+			target:     Target{URL: server.URL + `/{{randInt 1 (len (slice "abcd" seq))}}`, Template: true},
+			wantFailed: 2,
+			wantErr:    "failed to create request: ",
+		},
+		{
+			name: "data dependent bounds",
+			target: Target{
+				URL:      server.URL + "/{{randInt 1 (len .name)}}",
+				DataFile: writeTempFile(t, "names.json", `[{"name": "ann"}, {"name": ""}, {"name": "bob"}, {}, {"name": "cy"}]`),
+			},
+			wantFailed: 2,
+			wantErr:    "randInt max 0 is less than min 1",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.target.Options.Method = "GET"
+			s := StressConfig{Count: 5, Concurrency: 2, Quiet: true, Targets: []Target{tc.target}}
+			stats, err := RunStress(s, ioutil.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stats[0]) != 5 {
+				t.Fatalf("got %d requests, wanted 5", len(stats[0]))
+			}
+			failed := 0
+			for _, stat := range stats[0] {
+				if stat.Error == nil {
+					continue
+				}
+				failed++
+				if !strings.Contains(stat.Error.Error(), tc.wantErr) {
+					t.Errorf("got error %v, wanted %q", stat.Error, tc.wantErr)
+				}
+			}
+			if failed != tc.wantFailed {
+				t.Errorf("got %d failed requests, wanted %d", failed, tc.wantFailed)
+			}
+		})
+	}
+}
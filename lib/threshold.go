@@ -0,0 +1,284 @@
+package pewpew
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Threshold is a pass/fail condition on the summary of a test, written as
+// "METRIC OPERATOR VALUE", such as "p95 < 300ms" or "error-rate <= 1%".
+//
+// Supported metrics are:
+//   - latency: p50, p90, p99.9 or any other pNN percentile, mean, min, max; values are durations like 300ms
+//   - response time: the latency metrics prefixed with response-, like response-p99, measured
+//     from when each request was scheduled to be sent rather than when it actually was
+//   - error-rate: percentage of requests that failed or got a 5xx response, like 1%
+//   - errors: number of requests that failed to get a response
+//   - check-failures: number of responses that failed a Target's response checks
+//   - dropped: number of benchmark requests never sent because of MaxInFlight
+//   - status-5xx, status-404, etc.: number of responses with a status code or class
+//   - requests: total number of requests
+//   - rps: mean requests per second
+//
+// Supported operators are <, <=, >, >=, ==, and !=.
+type Threshold struct {
+	Metric   string
+	Operator string
+	//Value is in the metric's unit: nanoseconds for latency, percent for error-rate
+	Value float64
+	//Expression is the original text of the threshold
+	Expression string
+}
+
+// ThresholdResult is the outcome of checking a Threshold against a summary
+type ThresholdResult struct {
+	//Scope is what the threshold was checked against, such as "Global" or "Target 1"
+	Scope     string
+	Threshold Threshold
+	//Actual is the human friendly value of the metric
+	Actual string
+	Passed bool
+}
+
+var thresholdRegex = regexp.MustCompile(`^\s*([a-z0-9.\-]+)\s*(<=|>=|==|!=|<|>)\s*(\S+)\s*$`)
+
+// kinds of threshold metrics, which decide how values are parsed and shown
+const (
+	thresholdLatency = iota
+	thresholdPercent
+	thresholdCount
+	thresholdRate
+)
+
+// thresholdMetricKind finds the kind of the metric, or false if it isn't a known metric
+func thresholdMetricKind(metric string) (int, bool) {
+	if strings.HasPrefix(metric, "response-") {
+		kind, ok := thresholdMetricKind(metric[len("response-"):])
+		return kind, ok && kind == thresholdLatency
+	}
+	switch {
+	case metric == "mean" || metric == "min" || metric == "max":
+		return thresholdLatency, true
+	case strings.HasPrefix(metric, "p"):
+		p, err := strconv.ParseFloat(metric[1:], 64)
+		return thresholdLatency, err == nil && p >= 0 && p <= 100
+	case metric == "error-rate":
+		return thresholdPercent, true
+	case metric == "errors" || metric == "check-failures" || metric == "dropped" || metric == "requests":
+		return thresholdCount, true
+	case strings.HasPrefix(metric, "status-"):
+		_, ok := parseStatusClass(metric[len("status-"):])
+		return thresholdCount, ok
+	case metric == "rps":
+		return thresholdRate, true
+	}
+	return 0, false
+}
+
+// ParseStatusCodes parses a comma separated list of status codes and classes,
+// like "200, 201, 3xx", into a func reporting if a status code is one of them
+func ParseStatusCodes(spec string) (func(int) bool, error) {
+	var matchers []func(int) bool
+	for _, s := range strings.Split(spec, ",") {
+		matches, ok := parseStatusClass(strings.ToLower(strings.TrimSpace(s)))
+		if !ok {
+			return nil, fmt.Errorf("invalid status %q, must be a status code like 200 or a class like 2xx", strings.TrimSpace(s))
+		}
+		matchers = append(matchers, matches)
+	}
+	return func(code int) bool {
+		for _, matches := range matchers {
+			if matches(code) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// parseStatusClass parses a status code like "404" or a class like "5xx" into
+// a function that matches the status codes
+func parseStatusClass(str string) (func(int) bool, bool) {
+	if len(str) == 3 && strings.HasSuffix(str, "xx") && str[0] >= '1' && str[0] <= '5' {
+		class := int(str[0]-'0') * 100
+		return func(code int) bool { return code >= class && code < class+100 }, true
+	}
+	code, err := strconv.Atoi(str)
+	if err != nil || code < 100 || code > 599 {
+		return nil, false
+	}
+	return func(c int) bool { return c == code }, true
+}
+
+// ParseThreshold parses a threshold expression such as "p95 < 300ms"
+func ParseThreshold(expression string) (Threshold, error) {
+	matches := thresholdRegex.FindStringSubmatch(strings.ToLower(expression))
+	if matches == nil {
+		return Threshold{}, fmt.Errorf("failed to parse threshold %q, expected METRIC OPERATOR VALUE", expression)
+	}
+	t := Threshold{
+		Metric:     matches[1],
+		Operator:   matches[2],
+		Expression: strings.TrimSpace(expression),
+	}
+	kind, ok := thresholdMetricKind(t.Metric)
+	if !ok {
+		return Threshold{}, fmt.Errorf("unknown metric %q in threshold %q", t.Metric, expression)
+	}
+	valueStr := matches[3]
+	switch kind {
+	case thresholdLatency:
+		d, err := time.ParseDuration(valueStr)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("failed to parse duration in threshold %q: %w", expression, err)
+		}
+		t.Value = float64(d)
+	case thresholdPercent:
+		v, err := strconv.ParseFloat(strings.TrimSuffix(valueStr, "%"), 64)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("failed to parse percentage in threshold %q: %w", expression, err)
+		}
+		t.Value = v
+	default:
+		v, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("failed to parse number in threshold %q: %w", expression, err)
+		}
+		t.Value = v
+	}
+	return t, nil
+}
+
+// value finds the actual value of the Threshold's metric in the summary,
+// in the same unit as Threshold.Value, along with a human friendly version
+func (t Threshold) value(s RequestStatSummary) (float64, string) {
+	kind, _ := thresholdMetricKind(t.Metric)
+	var v float64
+	switch {
+	case t.Metric == "response-mean":
+		v = float64(s.avgResponseTime)
+	case t.Metric == "response-min":
+		v = float64(s.responseTimes.valueAtPercentile(0))
+	case t.Metric == "response-max":
+		v = float64(s.maxResponseTime)
+	case strings.HasPrefix(t.Metric, "response-"):
+		p, _ := strconv.ParseFloat(t.Metric[len("response-p"):], 64)
+		v = float64(s.responseTimes.valueAtPercentile(p))
+	case t.Metric == "mean":
+		v = float64(s.avgDuration)
+	case t.Metric == "min":
+		v = float64(s.minDuration)
+	case t.Metric == "max":
+		v = float64(s.maxDuration)
+	case kind == thresholdLatency:
+		p, _ := strconv.ParseFloat(t.Metric[1:], 64)
+		v = float64(s.latencies.valueAtPercentile(p))
+	case t.Metric == "error-rate":
+		v = s.errorRate() * 100
+	case t.Metric == "errors":
+		v = float64(s.errorCount)
+	case t.Metric == "check-failures":
+		v = float64(s.checkFailedCount)
+	case t.Metric == "dropped":
+		v = float64(s.droppedCount)
+	case t.Metric == "requests":
+		v = float64(s.requestCount())
+	case strings.HasPrefix(t.Metric, "status-"):
+		matches, _ := parseStatusClass(t.Metric[len("status-"):])
+		for code, count := range s.statusCodes {
+			if matches(code) {
+				v += float64(count)
+			}
+		}
+	case t.Metric == "rps":
+		v = s.avgRPS * 1000000000
+	}
+
+	switch kind {
+	case thresholdLatency:
+		return v, time.Duration(v).Round(time.Microsecond).String()
+	case thresholdPercent:
+		return v, fmt.Sprintf("%.2f%%", v)
+	case thresholdRate:
+		return v, fmt.Sprintf("%.2f", v)
+	default:
+		return v, fmt.Sprintf("%d", int(v))
+	}
+}
+
+// Check compares the summary against the Threshold
+func (t Threshold) Check(scope string, s RequestStatSummary) ThresholdResult {
+	actual, actualStr := t.value(s)
+	var passed bool
+	switch t.Operator {
+	case "<":
+		passed = actual < t.Value
+	case "<=":
+		passed = actual <= t.Value
+	case ">":
+		passed = actual > t.Value
+	case ">=":
+		passed = actual >= t.Value
+	case "==":
+		passed = actual == t.Value
+	case "!=":
+		passed = actual != t.Value
+	}
+	return ThresholdResult{
+		Scope:     scope,
+		Threshold: t,
+		Actual:    actualStr,
+		Passed:    passed,
+	}
+}
+
+// CheckThresholds parses each threshold expression and checks it against the summary
+func CheckThresholds(scope string, expressions []string, s RequestStatSummary) ([]ThresholdResult, error) {
+	results := make([]ThresholdResult, 0, len(expressions))
+	for _, expression := range expressions {
+		t, err := ParseThreshold(expression)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, t.Check(scope, s))
+	}
+	return results, nil
+}
+
+// CreateThresholdSummary creates a human friendly pass/fail table of threshold results
+func CreateThresholdSummary(results []ThresholdResult) string {
+	scopeWidth, expressionWidth := len("Scope"), len("Threshold")
+	for _, r := range results {
+		if len(r.Scope) > scopeWidth {
+			scopeWidth = len(r.Scope)
+		}
+		if len(r.Threshold.Expression) > expressionWidth {
+			expressionWidth = len(r.Threshold.Expression)
+		}
+	}
+	summary := "Thresholds\n"
+	summary += fmt.Sprintf("%-*s  %-*s  %-12s  %s\n", scopeWidth, "Scope", expressionWidth, "Threshold", "Actual", "Result")
+	passedCount := 0
+	for _, r := range results {
+		result := "FAIL"
+		if r.Passed {
+			result = "pass"
+			passedCount++
+		}
+		summary += fmt.Sprintf("%-*s  %-*s  %-12s  %s\n", scopeWidth, r.Scope, expressionWidth, r.Threshold.Expression, r.Actual, result)
+	}
+	summary += fmt.Sprintf("%d of %d thresholds passed\n", passedCount, len(results))
+	return summary
+}
+
+func validateThresholds(expressions []string) error {
+	for _, expression := range expressions {
+		if _, err := ParseThreshold(expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
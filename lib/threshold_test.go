@@ -0,0 +1,195 @@
+package pewpew
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       Threshold
+		expectErr  bool
+	}{
+		{name: "empty", expression: "", expectErr: true},
+		{name: "missing value", expression: "p95 <", expectErr: true},
+		{name: "unknown operator", expression: "p95 ~ 300ms", expectErr: true},
+		{name: "unknown metric", expression: "latency < 300ms", expectErr: true},
+		{name: "percentile over 100", expression: "p101 < 300ms", expectErr: true},
+		{name: "latency without unit", expression: "p95 < 300", expectErr: true},
+		{name: "invalid status class", expression: "status-6xx == 0", expectErr: true},
+		{name: "invalid count", expression: "errors == none", expectErr: true},
+		{name: "response time of a count", expression: "response-errors == 0", expectErr: true},
+		{
+			name:       "percentile",
+			expression: "p95 < 300ms",
+			want:       Threshold{Metric: "p95", Operator: "<", Value: float64(300 * time.Millisecond), Expression: "p95 < 300ms"},
+		},
+		{
+			name:       "fractional percentile without spaces",
+			expression: "p99.9<=1s",
+			want:       Threshold{Metric: "p99.9", Operator: "<=", Value: float64(time.Second), Expression: "p99.9<=1s"},
+		},
+		{
+			name:       "response time percentile",
+			expression: "response-p99 < 2s",
+			want:       Threshold{Metric: "response-p99", Operator: "<", Value: float64(2 * time.Second), Expression: "response-p99 < 2s"},
+		},
+		{
+			name:       "mean is case insensitive",
+			expression: " Mean > 10ms ",
+			want:       Threshold{Metric: "mean", Operator: ">", Value: float64(10 * time.Millisecond), Expression: "Mean > 10ms"},
+		},
+		{
+			name:       "error rate with percent sign",
+			expression: "error-rate < 1%",
+			want:       Threshold{Metric: "error-rate", Operator: "<", Value: 1, Expression: "error-rate < 1%"},
+		},
+		{
+			name:       "error rate without percent sign",
+			expression: "error-rate != 0.5",
+			want:       Threshold{Metric: "error-rate", Operator: "!=", Value: 0.5, Expression: "error-rate != 0.5"},
+		},
+		{
+			name:       "status class",
+			expression: "status-5xx == 0",
+			want:       Threshold{Metric: "status-5xx", Operator: "==", Value: 0, Expression: "status-5xx == 0"},
+		},
+		{
+			name:       "status code",
+			expression: "status-404 == 0",
+			want:       Threshold{Metric: "status-404", Operator: "==", Value: 0, Expression: "status-404 == 0"},
+		},
+		{
+			name:       "rps",
+			expression: "rps >= 100",
+			want:       Threshold{Metric: "rps", Operator: ">=", Value: 100, Expression: "rps >= 100"},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseThreshold(tc.expression)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error: %t, wanted: %t", (err != nil), tc.expectErr)
+			}
+			if err == nil && got != tc.want {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		match     []int
+		noMatch   []int
+		expectErr bool
+	}{
+		{name: "single code", spec: "404", match: []int{404}, noMatch: []int{400, 0}},
+		{name: "codes and classes", spec: "200, 201,5XX", match: []int{200, 201, 500, 599}, noMatch: []int{202, 404}},
+		{name: "empty", spec: "", expectErr: true},
+		{name: "invalid class", spec: "200,9xx", expectErr: true},
+		{name: "not a code", spec: "ok", expectErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := ParseStatusCodes(tc.spec)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			for _, code := range tc.match {
+				if !matches(code) {
+					t.Errorf("%d didn't match %q", code, tc.spec)
+				}
+			}
+			for _, code := range tc.noMatch {
+				if matches(code) {
+					t.Errorf("%d matched %q", code, tc.spec)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	summary := CreateRequestsStats([]RequestStat{
+		{StartTime: time.Unix(0, 0), EndTime: time.Unix(1, 0), Duration: 100 * time.Millisecond, StatusCode: 200},
+		{StartTime: time.Unix(0, 0), EndTime: time.Unix(1, 0), Duration: 200 * time.Millisecond, StatusCode: 200},
+		{StartTime: time.Unix(0, 0), EndTime: time.Unix(1, 0), Duration: 300 * time.Millisecond, StatusCode: 404},
+		{StartTime: time.Unix(0, 0), EndTime: time.Unix(2, 0), Duration: 400 * time.Millisecond, StatusCode: 503},
+		{StartTime: time.Unix(0, 0), EndTime: time.Unix(1, 0), Error: errors.New("connection refused")},
+	})
+	tests := []struct {
+		expression string
+		wantActual string
+		wantPass   bool
+	}{
+		{expression: "min == 100ms", wantActual: "100ms", wantPass: true},
+		{expression: "max < 400ms", wantActual: "400ms", wantPass: false},
+		{expression: "mean <= 250ms", wantActual: "250ms", wantPass: true},
+		//percentiles come from the latency histogram, so are accurate to within its bucket width
+		{expression: "p50 < 199ms", wantActual: "199.229ms", wantPass: false},
+		{expression: "p100 >= 398ms", wantActual: "398.459ms", wantPass: true},
+		{expression: "response-mean <= 250ms", wantActual: "250ms", wantPass: true},
+		{expression: "response-max < 400ms", wantActual: "400ms", wantPass: false},
+		{expression: "error-rate < 1%", wantActual: "40.00%", wantPass: false},
+		{expression: "error-rate <= 40%", wantActual: "40.00%", wantPass: true},
+		{expression: "errors == 1", wantActual: "1", wantPass: true},
+		{expression: "requests > 4", wantActual: "5", wantPass: true},
+		{expression: "dropped == 0", wantActual: "0", wantPass: true},
+		{expression: "status-2xx >= 2", wantActual: "2", wantPass: true},
+		{expression: "status-5xx == 0", wantActual: "1", wantPass: false},
+		{expression: "status-404 != 0", wantActual: "1", wantPass: true},
+		{expression: "rps >= 2", wantActual: "2.00", wantPass: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.expression, func(t *testing.T) {
+			t.Parallel()
+			results, err := CheckThresholds("Global", []string{tc.expression}, summary)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, wanted 1", len(results))
+			}
+			if results[0].Actual != tc.wantActual {
+				t.Errorf("got actual %s, wanted %s", results[0].Actual, tc.wantActual)
+			}
+			if results[0].Passed != tc.wantPass {
+				t.Errorf("got passed %t, wanted %t", results[0].Passed, tc.wantPass)
+			}
+		})
+	}
+
+	if _, err := CheckThresholds("Global", []string{"bogus"}, summary); err == nil {
+		t.Error("expected error for unparseable threshold")
+	}
+}
+
+func TestCreateThresholdSummary(t *testing.T) {
+	summary := CreateRequestsStats([]RequestStat{
+		{StartTime: time.Unix(0, 0), EndTime: time.Unix(1, 0), Duration: 100 * time.Millisecond, StatusCode: 200},
+	})
+	results, err := CheckThresholds("Target 1", []string{"p95 < 300ms", "status-2xx == 0"}, summary)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := CreateThresholdSummary(results)
+	want := "Thresholds\n" +
+		"Scope     Threshold        Actual        Result\n" +
+		"Target 1  p95 < 300ms      100ms         pass\n" +
+		"Target 1  status-2xx == 0  1             FAIL\n" +
+		"1 of 2 thresholds passed\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwanted:\n%s", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package pewpew
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimeSeriesBucket is a summary of the requests that started during one interval of a test
+type TimeSeriesBucket struct {
+	//Start is the beginning of the interval
+	Start time.Time `json:"start"`
+	//Offset is how far into the test the interval begins
+	Offset time.Duration `json:"offset"`
+	//Requests is how many requests were sent, including ones that failed
+	Requests int `json:"requests"`
+	//RPS is Requests per second of the interval
+	RPS float64 `json:"rps"`
+	//Errors is how many requests failed to get a response
+	Errors int `json:"errors"`
+	//Dropped is how many requests weren't sent because of a benchmark's MaxInFlight
+	Dropped int `json:"dropped"`
+	//StatusCodes is the count of each status code
+	StatusCodes map[int]int `json:"statusCodes"`
+
+	//latency of the requests that got a response
+	MeanDuration time.Duration `json:"meanDuration"`
+	P50Duration  time.Duration `json:"p50Duration"`
+	P90Duration  time.Duration `json:"p90Duration"`
+	P95Duration  time.Duration `json:"p95Duration"`
+	P99Duration  time.Duration `json:"p99Duration"`
+	MaxDuration  time.Duration `json:"maxDuration"`
+}
+
+// CreateTimeSeries splits the RequestStats into consecutive intervals by
+// when each request started, and summarizes each interval. Intervals without
+// any requests are included, so the series has no gaps.
+func CreateTimeSeries(requestStats []RequestStat, interval time.Duration) []TimeSeriesBucket {
+	if len(requestStats) == 0 || interval <= 0 {
+		return nil
+	}
+	start, end := requestStats[0].StartTime, requestStats[0].StartTime
+	for _, stat := range requestStats {
+		if stat.StartTime.Before(start) {
+			start = stat.StartTime
+		}
+		if stat.StartTime.After(end) {
+			end = stat.StartTime
+		}
+	}
+
+	buckets := make([]TimeSeriesBucket, int(end.Sub(start)/interval)+1)
+	latencies := make([]*latencyHistogram, len(buckets))
+	totalDurations := make([]time.Duration, len(buckets))
+	for i := range buckets {
+		buckets[i].Offset = time.Duration(i) * interval
+		buckets[i].Start = start.Add(buckets[i].Offset)
+		buckets[i].StatusCodes = make(map[int]int)
+		latencies[i] = newLatencyHistogram()
+	}
+	for _, stat := range requestStats {
+		i := int(stat.StartTime.Sub(start) / interval)
+		switch {
+		case stat.Dropped:
+			buckets[i].Dropped++
+			continue
+		case stat.Error != nil:
+			buckets[i].Errors++
+		default:
+			buckets[i].StatusCodes[stat.StatusCode]++
+			latencies[i].record(stat.Duration)
+			totalDurations[i] += stat.Duration
+		}
+		buckets[i].Requests++
+	}
+	for i := range buckets {
+		buckets[i].RPS = float64(buckets[i].Requests) / interval.Seconds()
+		if latencies[i].totalCount == 0 {
+			continue
+		}
+		buckets[i].MeanDuration = totalDurations[i] / time.Duration(latencies[i].totalCount)
+		buckets[i].P50Duration = latencies[i].valueAtPercentile(50)
+		buckets[i].P90Duration = latencies[i].valueAtPercentile(90)
+		buckets[i].P95Duration = latencies[i].valueAtPercentile(95)
+		buckets[i].P99Duration = latencies[i].valueAtPercentile(99)
+		buckets[i].MaxDuration = latencies[i].max
+	}
+	return buckets
+}
+
+// CreateTimeSeriesTable creates a compact human friendly table of a time series, one row per interval
+func CreateTimeSeriesTable(series []TimeSeriesBucket) string {
+	table := fmt.Sprintf("%8s %8s %9s %6s %7s %7s %7s %7s %7s %7s  %s\n",
+		"Offset", "Requests", "RPS", "Errors", "Dropped", "Mean ms", "p50 ms", "p90 ms", "p99 ms", "Max ms", "Status codes")
+	for _, bucket := range series {
+		row := fmt.Sprintf("%8s %8d %9.2f %6d %7d %7d %7d %7d %7d %7d  %s",
+			bucket.Offset,
+			bucket.Requests,
+			bucket.RPS,
+			bucket.Errors,
+			bucket.Dropped,
+			bucket.MeanDuration/time.Millisecond,
+			bucket.P50Duration/time.Millisecond,
+			bucket.P90Duration/time.Millisecond,
+			bucket.P99Duration/time.Millisecond,
+			bucket.MaxDuration/time.Millisecond,
+			formatStatusCodes(bucket.StatusCodes))
+		table += strings.TrimRight(row, " ") + "\n"
+	}
+	return table
+}
+
+// formatStatusCodes lists the counts of each status code in order, eg. "200:98 503:2"
+func formatStatusCodes(statusCodes map[int]int) string {
+	codes := make([]int, 0, len(statusCodes))
+	for code := range statusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	counts := make([]string, len(codes))
+	for i, code := range codes {
+		counts[i] = fmt.Sprintf("%d:%d", code, statusCodes[code])
+	}
+	return strings.Join(counts, " ")
+}
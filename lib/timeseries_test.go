@@ -0,0 +1,148 @@
+package pewpew
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateTimeSeries(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name         string
+		requestStats []RequestStat
+		interval     time.Duration
+		want         []TimeSeriesBucket
+	}{
+		{
+			name:         "no stats",
+			requestStats: []RequestStat{},
+			interval:     time.Second,
+			want:         nil,
+		},
+		{
+			name:         "invalid interval",
+			requestStats: []RequestStat{{StartTime: start, Duration: time.Millisecond, StatusCode: 200}},
+			interval:     0,
+			want:         nil,
+		},
+		{
+			name: "gap between intervals",
+			requestStats: []RequestStat{
+				{StartTime: start.Add(1500 * time.Millisecond), Error: errors.New("test error")},
+				{StartTime: start, Duration: 10 * time.Millisecond, StatusCode: 200},
+				{StartTime: start.Add(999 * time.Millisecond), Duration: 30 * time.Millisecond, StatusCode: 503},
+				{StartTime: start.Add(3 * time.Second), Duration: 20 * time.Millisecond, StatusCode: 200},
+				{StartTime: start.Add(3 * time.Second), Dropped: true},
+			},
+			interval: time.Second,
+			want: []TimeSeriesBucket{
+				{
+					Start:        start,
+					Offset:       0,
+					Requests:     2,
+					RPS:          2,
+					StatusCodes:  map[int]int{200: 1, 503: 1},
+					MeanDuration: 20 * time.Millisecond,
+					P50Duration:  histogramOf(10*time.Millisecond, 30*time.Millisecond).valueAtPercentile(50),
+					P90Duration:  histogramOf(10*time.Millisecond, 30*time.Millisecond).valueAtPercentile(90),
+					P95Duration:  histogramOf(10*time.Millisecond, 30*time.Millisecond).valueAtPercentile(95),
+					P99Duration:  histogramOf(10*time.Millisecond, 30*time.Millisecond).valueAtPercentile(99),
+					MaxDuration:  30 * time.Millisecond,
+				},
+				{
+					Start:       start.Add(time.Second),
+					Offset:      time.Second,
+					Requests:    1,
+					RPS:         1,
+					Errors:      1,
+					StatusCodes: map[int]int{},
+				},
+				{
+					Start:       start.Add(2 * time.Second),
+					Offset:      2 * time.Second,
+					StatusCodes: map[int]int{},
+				},
+				{
+					Start:        start.Add(3 * time.Second),
+					Offset:       3 * time.Second,
+					Requests:     1,
+					RPS:          1,
+					Dropped:      1,
+					StatusCodes:  map[int]int{200: 1},
+					MeanDuration: 20 * time.Millisecond,
+					P50Duration:  20 * time.Millisecond,
+					P90Duration:  20 * time.Millisecond,
+					P95Duration:  20 * time.Millisecond,
+					P99Duration:  20 * time.Millisecond,
+					MaxDuration:  20 * time.Millisecond,
+				},
+			},
+		},
+		{
+			name: "sub-second interval",
+			requestStats: []RequestStat{
+				{StartTime: start, Duration: time.Millisecond, StatusCode: 200},
+				{StartTime: start.Add(100 * time.Millisecond), Duration: time.Millisecond, StatusCode: 200},
+			},
+			interval: 100 * time.Millisecond,
+			want: []TimeSeriesBucket{
+				{
+					Start:        start,
+					Requests:     1,
+					RPS:          10,
+					StatusCodes:  map[int]int{200: 1},
+					MeanDuration: time.Millisecond,
+					P50Duration:  time.Millisecond,
+					P90Duration:  time.Millisecond,
+					P95Duration:  time.Millisecond,
+					P99Duration:  time.Millisecond,
+					MaxDuration:  time.Millisecond,
+				},
+				{
+					Start:        start.Add(100 * time.Millisecond),
+					Offset:       100 * time.Millisecond,
+					Requests:     1,
+					RPS:          10,
+					StatusCodes:  map[int]int{200: 1},
+					MeanDuration: time.Millisecond,
+					P50Duration:  time.Millisecond,
+					P90Duration:  time.Millisecond,
+					P95Duration:  time.Millisecond,
+					P99Duration:  time.Millisecond,
+					MaxDuration:  time.Millisecond,
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := CreateTimeSeries(tc.requestStats, tc.interval)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCreateTimeSeriesTable(t *testing.T) {
+	series := []TimeSeriesBucket{
+		{Offset: 0, Requests: 3, RPS: 3, StatusCodes: map[int]int{503: 1, 200: 2}, MeanDuration: 12 * time.Millisecond},
+		{Offset: time.Second, Errors: 1, Requests: 1, RPS: 1, StatusCodes: map[int]int{}},
+	}
+	table := CreateTimeSeriesTable(series)
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, wanted a header and 2 rows:\n%s", len(lines), table)
+	}
+	if !strings.HasSuffix(lines[1], "200:2 503:1") {
+		t.Errorf("got row %q, wanted status codes in order", lines[1])
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[2]), "1s") {
+		t.Errorf("got row %q, wanted it to start with the offset", lines[2])
+	}
+}
@@ -120,6 +120,13 @@ func buildRequest(t Target) (http.Request, error) {
 	}
 
 	req.Header.Set("User-Agent", t.Options.UserAgent)
+	for key, val := range t.Options.RawHeaders {
+		if strings.EqualFold(key, "Host") {
+			req.Host = val
+			continue
+		}
+		req.Header.Set(key, val)
+	}
 
 	//add cookies
 	if t.Options.Cookies != "" {